@@ -36,6 +36,8 @@ const (
 type Action interface {
 	Name() string              // defimpl:"read name"
 	Description() string      // defimpl:"read description"
+	// Beats is how many beats of music the Action normally takes.
+	Beats() int                // defimpl:"read beats"
 	AddFormationAction(...FormationAction)     // defimpl:"append formationActions"
 	DoFormationActions(func(FormationAction) bool)         // defimpl:"iterate formationActions"
 	GetFormationAction(reasoning.FormationType) FormationAction
@@ -80,13 +82,59 @@ func FindAction(actionName string) Action {
 	return nil
 }
 
-func defineAction(name string, description string) {
+// ActionsAtOrBelow returns the Actions which can be done at the
+// specified Level, those with at least one FormationAction at or below
+// it, in the order they were defined.
+func ActionsAtOrBelow(level Level) []Action {
+	result := []Action{}
+	for _, a := range AllActions {
+		if lowestLevel(a) <= level {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// AtLevel returns an Action like a but with only those of its
+// FormationActions which are at or below level, so that it can only be
+// done from the Formations that a dancer at that Level would know.  If
+// all of a's FormationActions are at or below level then a itself is
+// returned.
+func AtLevel(a Action, level Level) Action {
+	restricted := &ActionImpl{
+		name: a.Name(),
+		description: a.Description(),
+		beats: a.Beats(),
+		formationActions: []FormationAction{},
+	}
+	all := true
+	a.DoFormationActions(func(fa FormationAction) bool {
+		if fa.Level() <= level {
+			restricted.AddFormationAction(fa)
+		} else {
+			all = false
+		}
+		return true
+	})
+	if all {
+		return a
+	}
+	return restricted
+}
+
+// defineAction defines a new Action.  If beats is 0 then the
+// Action's duration is looked up with StandardBeats.
+func defineAction(name string, beats int, description string) {
 	if FindAction(name) != nil {
 		panic(fmt.Sprintf("Attempt to redefine action %s", name))
 	}
+	if beats == 0 {
+		beats = StandardBeats(name)
+	}
 	AllActions = append(AllActions, &ActionImpl{
 		name: name,
 		description: description,
+		beats: beats,
 		formationActions: []FormationAction{},
 	}	)
 }
@@ -99,8 +147,15 @@ type FormationAction interface {
 	Level() Level                              // defimpl:"read level"
 	FormationType() reasoning.FormationType   // defimpl:"read formationType"
 	// DoItFunc is a function that will perform the action.
-	DoItFunc() func(reasoning.Formation)       // defimpl:"read doItFunc"
-	DoIt(reasoning.Formation)
+	// It records the Dancers' new positions and directions in the
+	// Transaction rather than moving the Dancers itself.
+	DoItFunc() func(reasoning.Formation, *Transaction) error       // defimpl:"read doItFunc"
+	// DoIt performs the action from the Formation.  It returns an
+	// ErrNotApplicable if the FormationAction doesn't apply to it.
+	DoIt(reasoning.Formation) error
+	// Plan records in the Transaction how the Dancers of the
+	// Formation would move, without moving them.
+	Plan(reasoning.Formation, *Transaction) error
 	String() string
 	ApplicableTo(reasoning.Formation) bool
 	ApplicableToFormationType(reasoning.FormationType) bool
@@ -125,21 +180,34 @@ func (fa *FormationActionImpl) ApplicableTo(f reasoning.Formation) bool {
 	return fa.ApplicableToFormationType(reasoning.FormationType(reflect.TypeOf(f)))
 }
 
-func (fa *FormationActionImpl) DoIt(f reasoning.Formation) {
+func (fa *FormationActionImpl) DoIt(f reasoning.Formation) error {
+	tx := NewTransaction()
+	if err := fa.Plan(f, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (fa *FormationActionImpl) Plan(f reasoning.Formation, tx *Transaction) error {
 	if !fa.ApplicableTo(f) {
-		panic(fmt.Sprintf("%s doesn't apply to %#v", fa, f))
+		return &ErrNotApplicable{
+			Action: fa.Action(),
+			FormationAction: fa,
+			Formation: f,
+		}
 	}
-	fa.doItFunc(f)
+	return fa.doItFunc(f, tx)
 }
 
 
 func defineFormationAction(actionName string, level Level,
 	formationType reasoning.FormationType,
-	doit func(reasoning.Formation)) {
+	doit func(reasoning.Formation, *Transaction) error) {
 	a := FindAction(actionName)
 	if a == nil {
 		a = &ActionImpl{
 			name: actionName,
+			beats: StandardBeats(actionName),
 			formationActions: []FormationAction{} }
 		AllActions = append(AllActions, a)
 	}
@@ -1,5 +1,6 @@
 package action
 
+import "math"
 import "testing"
 import "squaredance/dancer"
 import "squaredance/geometry"
@@ -37,3 +38,104 @@ func TestBreating(t *testing.T) {
 	check_distance(dancers[1], dancers[3])
 }
 
+
+func TestBreatheKeepsLinesStraight(t *testing.T) {
+	// A crowded line of four, slightly ragged, facing the caller's
+	// left:
+	dancers := dancer.MakeSomeDancers(4)
+	for i, d := range dancers {
+		d.Move(geometry.NewPositionDownLeft(geometry.Down(0.5 * float32(i)),
+			geometry.Left(0.05 * float32(i % 2))),
+			geometry.Direction1)
+	}
+	report := breathe(dancers, geometry.Origin)
+	if !report.Converged {
+		t.Errorf("Breathe didn't converge after %d iterations", report.Iterations)
+	}
+	if want, got := 1, report.Rows; got != want {
+		t.Errorf("Wrong number of rows: want %d, got %d", want, got)
+	}
+	if want, got := 4, report.Columns; got != want {
+		t.Errorf("Wrong number of columns: want %d, got %d", want, got)
+	}
+	if center := dancers.Center(); !center.Equal(geometry.Origin) {
+		t.Errorf("Not centered: %v", center)
+	}
+	for i := 1; i < len(dancers); i++ {
+		if !dancers[i].Position().Left.Equal(dancers[0].Position().Left) {
+			t.Errorf("Line isn't straight: %v, %v", dancers[0], dancers[i])
+		}
+		if want, got := geometry.CoupleDistance, dancer.Distance(dancers[i-1], dancers[i]); math.Abs(float64(want - got)) > 0.001 {
+			t.Errorf("Wrong spacing: want %f, got %f", want, got)
+		}
+	}
+}
+
+func TestBreatheCoincident(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(2)
+	for _, d := range dancers {
+		d.Move(geometry.Origin, geometry.Direction0)
+	}
+	report := Breathe(dancers)
+	if want, got := 1, len(report.Coincident); got != want {
+		t.Errorf("Wrong number of coincident pairs: want %d, got %d", want, got)
+	}
+}
+
+func TestBreatheSquaredSet(t *testing.T) {
+	// A squared set is already well spaced.
+	set := dancer.NewSquaredSet(4)
+	before := dancer.Positions(set.Dancers()...)
+	report := BreatheSet(set)
+	if want, got := 4, report.Rows; got != want {
+		t.Errorf("Wrong number of rows: want %d, got %d", want, got)
+	}
+	for i, d := range set.Dancers() {
+		if !before[i].Equal(d.Position()) {
+			t.Errorf("%s moved from %v to %v", d, before[i], d.Position())
+		}
+	}
+}
+
+func TestBreatheLegalFormations(t *testing.T) {
+	type spot struct {
+		down, left float32
+		direction geometry.Direction
+	}
+	for name, spots := range map[string][]spot{
+		// Centers in a MiniWave, points facing in:
+		"Diamond": {
+			{ -1, 0, geometry.Direction0 },
+			{ 0, -0.5, geometry.Direction1 },
+			{ 0, 0.5, geometry.Direction3 },
+			{ 1, 0, geometry.Direction2 },
+		},
+		// A wave of four between two couples:
+		"QuarterTag": {
+			{ -1, -0.5, geometry.Direction0 },
+			{ -1, 0.5, geometry.Direction0 },
+			{ 0, -1.5, geometry.Direction0 },
+			{ 0, -0.5, geometry.Direction2 },
+			{ 0, 0.5, geometry.Direction0 },
+			{ 0, 1.5, geometry.Direction2 },
+			{ 1, -0.5, geometry.Direction2 },
+			{ 1, 0.5, geometry.Direction2 },
+		},
+	} {
+		dancers := dancer.MakeSomeDancers(len(spots))
+		for i, s := range spots {
+			dancers[i].Move(geometry.NewPositionDownLeft(geometry.Down(s.down), geometry.Left(s.left)),
+				s.direction)
+		}
+		before := dancer.Positions(dancers...)
+		report := Breathe(dancers)
+		if len(report.Moved) > 0 {
+			t.Errorf("%s: Breathe moved %v", name, report.Moved)
+		}
+		for i, d := range dancers {
+			if !before[i].Equal(d.Position()) {
+				t.Errorf("%s: %s moved from %v to %v", name, d, before[i], d.Position())
+			}
+		}
+	}
+}
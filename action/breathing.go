@@ -1,50 +1,317 @@
 package action
 
 import "math"
+import "sort"
 import "squaredance/dancer"
 import "squaredance/geometry"
+import "squaredance/reasoning"
+
+
+// MaxBreathingIterations bounds how many times Breathe will try to
+// respace the dancers.
+const MaxBreathingIterations = 10
+
+// breathingTolerance is how close two coordinates must be for the
+// dancers to be considered to be in the same row or column.
+const breathingTolerance float32 = geometry.CoupleDistance / 10
+
+// alignmentTolerance is how close two coordinates must be for dancers
+// in the same recognized Formation to be considered to be in the same
+// row or column.
+const alignmentTolerance float32 = geometry.CoupleDistance / 4
+
+// breathingSlack is added to the spacings that Breathe makes so that
+// float32 rounding never leaves dancers a hair closer than
+// CoupleDistance.
+const breathingSlack float32 = 0.00001
+
+
+// BreathingReport describes what Breathe did.
+type BreathingReport struct {
+	// Axis is the Direction of the rows that the dancers were
+	// found to be in.  The columns are a quarter turn from that.
+	Axis geometry.Direction
+	Rows int
+	Columns int
+	Iterations int
+	// Converged is false if the dancers still hadn't settled
+	// down after MaxBreathingIterations.
+	Converged bool
+	// Moved lists the dancers whose positions were changed.
+	Moved dancer.Dancers
+	// Coincident lists pairs of dancers which are on the same
+	// spot.  Breathe can't tell which way to spread them apart.
+	Coincident [][2]dancer.Dancer
+}
 
 
 // Breathe finds dancers that are too close together and spreads them
-// apart.
-func Breathe(dancers dancer.Dancers) {
-	any := true
-	for any {
-		any = false
-		for i, d1 := range dancers {
-			for j := i+1; j < len(dancers); j++ {
-				d2 := dancers[j]
-				distance := dancer.Distance(d1, d2)
-				spread_by := geometry.CoupleDistance - distance
-				if  spread_by <= 0  {
-					continue
+// apart.  If no two dancers are closer than CoupleDistance then nobody
+// moves.  Otherwise the dancers are grouped into rows and columns,
+// which are straightened, and rows and columns are spread apart only
+// as far as is needed for every dancer to be at least CoupleDistance
+// from every other.  Dancers that are in the same recognized
+// Formation and nearly in line are kept in line.  The order of the
+// rows and columns is preserved, so lines stay straight and columns
+// stay aligned.  The center of the dancers does not move.
+func Breathe(dancers dancer.Dancers) *BreathingReport {
+	return breathe(dancers, dancers.Center())
+}
+
+// BreatheSet is like Breathe but keeps the dancers of the Set
+// centered on its FlagpoleCenter.
+func BreatheSet(set dancer.Set) *BreathingReport {
+	return breathe(set.Dancers(), set.FlagpoleCenter())
+}
+
+func breathe(dancers dancer.Dancers, center geometry.Position) *BreathingReport {
+	report := &BreathingReport{
+		Moved: dancer.Dancers{},
+		Coincident: [][2]dancer.Dancer{},
+	}
+	if len(dancers) == 0 {
+		report.Converged = true
+		return report
+	}
+	report.Axis = gridAxis(dancers)
+	// Work in a coordinate system where the rows run along the
+	// Down axis.
+	origin := dancers.Center()
+	downs := make([]float32, len(dancers))
+	lefts := make([]float32, len(dancers))
+	for i, d := range dancers {
+		p := d.Position().Subtract(origin)
+		if report.Axis != geometry.Direction0 {
+			p = p.Rotate(report.Axis.Inverse())
+		}
+		downs[i] = float32(p.Down)
+		lefts[i] = float32(p.Left)
+	}
+	aligned := alignedPairs(dancers)
+	columns := clusters(downs, aligned)
+	rows := clusters(lefts, aligned)
+	if !tooClose(dancers) {
+		report.Rows = len(rows)
+		report.Columns = len(columns)
+		report.Converged = true
+		report.Coincident = coincident(dancers, rows, columns)
+		return report
+	}
+	for report.Iterations < MaxBreathingIterations {
+		report.Iterations += 1
+		var changed1, changed2 bool
+		columns, changed1 = respace(downs, lefts, aligned)
+		rows, changed2 = respace(lefts, downs, aligned)
+		if !(changed1 || changed2) {
+			report.Converged = true
+			break
+		}
+	}
+	report.Rows = len(rows)
+	report.Columns = len(columns)
+	// Put the dancers back, centered on center.
+	positions := make([]geometry.Position, len(dancers))
+	for i := range dancers {
+		positions[i] = geometry.NewPositionDownLeft(
+			geometry.Down(downs[i]), geometry.Left(lefts[i]))
+		if report.Axis != geometry.Direction0 {
+			positions[i] = positions[i].Rotate(report.Axis)
+		}
+	}
+	shift := center.Subtract(geometry.Center(positions...))
+	for i, d := range dancers {
+		p := positions[i].Add(shift)
+		if !p.Equal(d.Position()) {
+			report.Moved = append(report.Moved, d)
+		}
+		d.Move(p, d.Direction())
+	}
+	report.Coincident = coincident(dancers, rows, columns)
+	return report
+}
+
+// tooClose returns true if any two of the dancers are closer than
+// CoupleDistance.
+func tooClose(dancers dancer.Dancers) bool {
+	for i, d1 := range dancers {
+		for _, d2 := range dancers[i+1:] {
+			if dancer.Distance(d1, d2) < geometry.CoupleDistance - breathingSlack {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// coincident returns the pairs of dancers that are in the same row
+// and column.
+func coincident(dancers dancer.Dancers, rows, columns [][]int) [][2]dancer.Dancer {
+	result := [][2]dancer.Dancer{}
+	rowOf := map[int]int{}
+	for r, row := range rows {
+		for _, i := range row {
+			rowOf[i] = r
+		}
+	}
+	for _, column := range columns {
+		for x, i := range column {
+			for _, j := range column[x+1:] {
+				if rowOf[i] == rowOf[j] {
+					result = append(result, [2]dancer.Dancer{ dancers[i], dancers[j] })
 				}
-				any = true
-				midpoint := dancer.Dancers{d1, d2}.Center()
-				spread(dancers, midpoint,
-					geometry.NewPosition(midpoint.Direction(d1.Position()),
-						spread_by))
-				break
 			}
-			if any {
-				break
+		}
+	}
+	return result
+}
+
+// alignedPairs returns, for each pair of indices into dancers, whether
+// those dancers are in the same recognized Formation.
+func alignedPairs(dancers dancer.Dancers) map[[2]int]bool {
+	index := map[dancer.Dancer]int{}
+	for i, d := range dancers {
+		index[d] = i
+	}
+	aligned := map[[2]int]bool{}
+	for _, f := range reasoning.Recognize(dancers) {
+		for _, d1 := range f.Dancers() {
+			for _, d2 := range f.Dancers() {
+				aligned[[2]int{ index[d1], index[d2] }] = true
+			}
+		}
+	}
+	return aligned
+}
+
+// gridAxis determines the orientation of the rows and columns of the
+// dancers from their facing directions.  Directions that differ by a
+// quarter turn describe the same grid.
+func gridAxis(dancers dancer.Dancers) geometry.Direction {
+	var x, y float64
+	for _, d := range dancers {
+		angle := 4 * float64(d.Direction()) * 2 * math.Pi
+		x += math.Cos(angle)
+		y += math.Sin(angle)
+	}
+	if math.Abs(x) < 0.001 && math.Abs(y) < 0.001 {
+		return geometry.Direction0
+	}
+	axis := math.Atan2(y, x) / (2 * math.Pi) / 4
+	// Avoid needless rounding errors from rotating by almost
+	// nothing:
+	if math.Abs(axis) < 0.0001 {
+		return geometry.Direction0
+	}
+	return geometry.Direction(axis)
+}
+
+// clusters groups the coordinates into rows or columns, as lists of
+// indices into coordinates, ordered by coordinate.  Coordinates within
+// breathingTolerance of each other are in the same cluster, as are
+// those of dancers in the same Formation that are within
+// alignmentTolerance.
+func clusters(coordinates []float32, aligned map[[2]int]bool) [][]int {
+	// Union find:
+	parent := make([]int, len(coordinates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range coordinates {
+		for j := i + 1; j < len(coordinates); j++ {
+			gap := float32(math.Abs(float64(coordinates[i] - coordinates[j])))
+			if gap <= breathingTolerance || (aligned[[2]int{ i, j }] && gap <= alignmentTolerance) {
+				parent[find(i)] = find(j)
 			}
 		}
 	}
+	byRoot := map[int][]int{}
+	roots := []int{}
+	for i := range coordinates {
+		r := find(i)
+		if _, ok := byRoot[r]; !ok {
+			roots = append(roots, r)
+		}
+		byRoot[r] = append(byRoot[r], i)
+	}
+	result := [][]int{}
+	for _, r := range roots {
+		result = append(result, byRoot[r])
+	}
+	mean := func(cluster []int) float32 {
+		sum := float32(0)
+		for _, i := range cluster {
+			sum += coordinates[i]
+		}
+		return sum / float32(len(cluster))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return mean(result[i]) < mean(result[j])
+	})
+	return result
 }
 
-// spread moves dancers away from midpoiint by + or - vector, as appropriate.
-func spread(dancers dancer.Dancers, midpoint geometry.Position, vector geometry.Position) {
-	vdir := vector.Angle()
-	vodir := vdir.Opposite()
-	ovector := geometry.NewPositionDownLeft(0.0, 0.0).Subtract(vector)
-	for _, dancer := range dancers {
-		ddir := midpoint.Direction(dancer.Position())
-		if math.Abs(float64(ddir - vdir)) < math.Abs(float64(ddir - vodir)) {
-			dancer.MoveBy(vector)
-		} else if math.Abs(float64(ddir - vdir)) > math.Abs(float64(ddir - vodir)){
-			dancer.MoveBy(ovector)
+// respace moves each coordinate to the mean of its cluster, and then
+// moves the clusters, in order, only as far apart as is needed for
+// every pair of dancers in different clusters to be at least
+// CoupleDistance apart.  others are the dancers' coordinates along
+// the other axis.  The clusters are returned along with whether any
+// coordinate changed.
+func respace(coordinates []float32, others []float32, aligned map[[2]int]bool) ([][]int, bool) {
+	cs := clusters(coordinates, aligned)
+	means := make([]float32, len(cs))
+	for c, cluster := range cs {
+		for _, i := range cluster {
+			means[c] += coordinates[i]
+		}
+		means[c] /= float32(len(cluster))
+	}
+	changed := false
+	set := func(i int, value float32) {
+		if math.Abs(float64(value - coordinates[i])) > float64(breathingTolerance) / 100 {
+			changed = true
+		}
+		coordinates[i] = value
+	}
+	values := make([]float32, len(cs))
+	for c, cluster := range cs {
+		if c == 0 {
+			values[0] = means[0]
+		} else {
+			// Keep the original gap unless someone is too
+			// close to a dancer in an earlier cluster.
+			values[c] = values[c-1] + means[c] - means[c-1]
+			for k := 0; k < c; k++ {
+				for _, i := range cs[k] {
+					for _, j := range cluster {
+						need := spacingNeeded(others[i] - others[j])
+						if values[k] + need > values[c] {
+							values[c] = values[k] + need
+						}
+					}
+				}
+			}
+		}
+		for _, i := range cluster {
+			set(i, values[c])
 		}
 	}
+	return cs, changed
 }
 
+// spacingNeeded returns how far apart two dancers which are offset by
+// other along one axis must be along the other for them to be
+// CoupleDistance apart.
+func spacingNeeded(other float32) float32 {
+	other = float32(math.Abs(float64(other)))
+	if other >= geometry.CoupleDistance {
+		return 0
+	}
+	return float32(math.Sqrt(float64(geometry.CoupleDistance * geometry.CoupleDistance - other * other))) + breathingSlack
+}
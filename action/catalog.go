@@ -4,8 +4,10 @@ package action
 
 import "fmt"
 import "os"
+import "path/filepath"
 import "sort"
 import "html/template"
+import "squaredance/dancer"
 import "squaredance/reasoning"
 
 
@@ -35,9 +37,9 @@ func (cs catalogSort) Less (i, j int) bool {
 }
 
 
-// WriteCatalog writes an HTML file listing all FormationActions for
-// the specified level.
-func WriteCatalog(level Level) {
+// WriteCatalog writes an HTML file in the directory dir listing all
+// FormationActions for the specified level.
+func WriteCatalog(dir string, level Level) error {
 	fas := []*dancersTemplateArg{}
 	// Filter by level:
 	for _, action := range AllActions {
@@ -49,20 +51,120 @@ func WriteCatalog(level Level) {
 		})
 	}
 	sort.Sort(catalogSort(fas))
-	f, err := os.Create(fmt.Sprintf("catalog-%s.html", level))
+	f, err := os.Create(filepath.Join(dir, catalogFileName(level)))
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
-	defer f.Close()
 	err = html_page.Execute(f, html_page_arg {
 		Level: level,
 		DancersTemplateArgs: fas,
 	})
-	if err != nil {
-		fmt.Println(err)
-		return
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// catalogFileName returns the name of the catalog file for the Level.
+func catalogFileName(level Level) string {
+	return fmt.Sprintf("catalog-%s.html", level)
+}
+
+// catalogAfter describes the dancers after a FormationAction has been
+// done to a sample of its FormationType.
+type catalogAfter struct {
+	svg_id string
+	dancers dancer.Dancers
+	err error
+	formations []reasoning.Formation
+}
+
+// newCatalogAfter does the FormationAction to a fresh sample of its
+// FormationType, so that the sample shown in the Before column isn't
+// disturbed.
+func newCatalogAfter(fa FormationAction) *catalogAfter {
+	ca := &catalogAfter{
+		svg_id: fmt.Sprintf("%s-%d-%s-end",
+			fa.Action().Name(),
+			fa.Level(),
+			fa.FormationType().Name()),
+	}
+	sample := reasoning.MakeSampleFormation(fa.FormationType())
+	if sample == nil {
+		return nil
+	}
+	ca.dancers = sample.Dancers()
+	if ca.err = fa.DoIt(sample); ca.err != nil {
+		return ca
+	}
+	ca.formations = reasoning.Recognize(ca.dancers)
+	return ca
+}
+
+func (ca *catalogAfter) SVGId() string {
+	return ca.svg_id
+}
+
+// Sample returns the catalogAfter itself so that it can be passed to
+// DancersSVGTemplate, which draws .Sample.Dancers.
+func (ca *catalogAfter) Sample() *catalogAfter {
+	return ca
+}
+
+func (ca *catalogAfter) Dancers() dancer.Dancers {
+	return ca.dancers
+}
+
+// Err returns the error, if any, from doing the FormationAction.
+func (ca *catalogAfter) Err() error {
+	return ca.err
+}
+
+// FormationNames returns the names of the Formations that were
+// recognized after the FormationAction was done.  The Handedness of
+// those Formations which have one is included.
+func (ca *catalogAfter) FormationNames() []string {
+	names := []string{}
+	for _, f := range ca.formations {
+		names = append(names, reasoning.FormationName(f))
+	}
+	return names
+}
+
+// Recognized returns true if the FormationAction succeeded and every
+// dancer is in one of the recognized Formations.  A lone dancer is
+// always recognized.
+func (ca *catalogAfter) Recognized() bool {
+	if ca.err != nil {
+		return false
 	}
+	if len(ca.dancers) < 2 {
+		return true
+	}
+	count := 0
+	for _, f := range ca.formations {
+		count += f.NumberOfDancers()
+	}
+	return count == len(ca.dancers)
+}
+
+// UnrecognizedResults returns the FormationActions at the specified
+// Level which, when done to a sample of their FormationType, fail or
+// leave the dancers in no recognized Formation.
+func UnrecognizedResults(level Level) []FormationAction {
+	bad := []FormationAction{}
+	for _, action := range AllActions {
+		action.DoFormationActions(func(fa FormationAction) bool {
+			if fa.Level() != level {
+				return true
+			}
+			if ca := newCatalogAfter(fa); ca != nil && !ca.Recognized() {
+				bad = append(bad, fa)
+			}
+			return true
+		})
+	}
+	return bad
 }
 
 type html_page_arg struct {
@@ -74,10 +176,11 @@ type dancersTemplateArg struct {
 	FormationAction FormationAction
 	svg_id string
 	sample reasoning.Formation
+	after *catalogAfter
 }
 
 func newDancersTemplateArg(fa FormationAction) *dancersTemplateArg {
-	return &dancersTemplateArg {
+	dta := &dancersTemplateArg {
 		FormationAction: fa,
 		svg_id: fmt.Sprintf("%s-%d-%s-start",
 			fa.Action().Name(),
@@ -85,6 +188,16 @@ func newDancersTemplateArg(fa FormationAction) *dancersTemplateArg {
 			fa.FormationType().Name()),
 		sample: reasoning.MakeSampleFormation(fa.FormationType()),
 	}
+	if dta.sample != nil {
+		dta.after = newCatalogAfter(fa)
+	}
+	return dta
+}
+
+// After returns the result of doing the FormationAction to its
+// sample, or nil if there is no sample.
+func (dta *dancersTemplateArg) After() *catalogAfter {
+	return dta.after
 }
 
 func (dta *dancersTemplateArg) SVGId() string {
@@ -115,6 +228,9 @@ func (dta *dancersTemplateArg) Name() string {
 var html_page = template.Must(template.New("html_page").Funcs(
 	reasoning.MergeTemplateFuncs(
 		template.FuncMap{
+			"CatalogIndexFileName": func() string {
+				return CatalogIndexFileName
+			},
 			"NewDancersSVGTemplateArg":
 			func (fa FormationAction) reasoning.DancersSVGTemplateArg {
 				return newDancersTemplateArg(fa)
@@ -134,26 +250,18 @@ svg {
     background-color: lightslategray;
     stroke: black;
 }
-    </style>
-    <script type="text/javascript"
-            src="https://marknahabedian.github.io/SquareDanceFormationDiagrams/dancers.js">
-    </script>
-    <script type="text/javascript">
-function contentLoaded() {
-      {{range .DancersTemplateArgs -}}
-        {{if .HasSample -}}
-          {{- template "DancersSVGTemplate" . -}}
-        {{end -}}
-      {{- end -}}
+.unrecognized {
+    color: red;
 }
-
-document.addEventListener("DOMContentLoaded", contentLoaded, false);
-    </script>
+    </style>
   </head>
   <body>
     <h1>
       Catalog of {{.Level}} level Formation Actions
     </h1>
+    <p>
+      <a href="{{CatalogIndexFileName}}#{{.Level}}">All levels</a>
+    </p>
     <table>
       <thead>
         <tr>
@@ -164,19 +272,35 @@ document.addEventListener("DOMContentLoaded", contentLoaded, false);
         </tr>
       </thead>
       {{range .DancersTemplateArgs -}}
-        <tr>
+        <tr id="{{.FormationAction.IdString}}">
           <td>{{.FormationAction.Action.Name}}</td>
           <td>{{.FormationAction.FormationType.Name}}</td>
           <td>
-            {{if .Sample -}}
-              <svg id="{{.SVGId}}"></svg>
+            {{if .HasSample -}}
+              {{- template "DancersSVGTemplate" . -}}
             {{- else -}}
               <span>
                 {{printf "%s" .FormationAction.FormationType.Name}}
               </span>
             {{- end}}
           </td>
-          <td></td>
+          <td>
+            {{with .After -}}
+              {{if .Err -}}
+                <span class="unrecognized">{{.Err}}</span>
+              {{- else -}}
+                {{- template "DancersSVGTemplate" . -}}
+                <br/>
+                {{if .Recognized -}}
+                  <span>
+                {{- else -}}
+                  <span class="unrecognized">Unrecognized:
+                {{- end}}
+                  {{range .FormationNames}}{{.}} {{end -}}
+                </span>
+              {{- end}}
+            {{- end}}
+          </td>
         </tr>
       {{- end}}
     </table>
@@ -184,3 +308,165 @@ document.addEventListener("DOMContentLoaded", contentLoaded, false);
 </html>
 ` + reasoning.DancersSVGTemplate))
 
+
+
+// CatalogLevels are the Levels that WriteAllCatalogs covers.
+var CatalogLevels = []Level{
+	Primitive, Basic1, Basic2, Mainstream, Plus,
+	A_1, A_2, C_1, C_2, C_3A, C_3B, C_4,
+}
+
+// CatalogIndexFileName is the name of the file written by
+// WriteCatalogIndex.
+const CatalogIndexFileName = "catalog.html"
+
+// WriteAllCatalogs writes the catalog for each of CatalogLevels that
+// has any FormationActions and an index to them, all in the directory
+// dir.
+func WriteAllCatalogs(dir string) error {
+	for _, level := range CatalogLevels {
+		if !hasFormationActions(level) {
+			continue
+		}
+		if err := WriteCatalog(dir, level); err != nil {
+			return err
+		}
+	}
+	return WriteCatalogIndex(dir)
+}
+
+// hasFormationActions returns true if any Action has a FormationAction
+// at exactly the Level.
+func hasFormationActions(level Level) bool {
+	found := false
+	for _, a := range AllActions {
+		a.DoFormationActions(func(fa FormationAction) bool {
+			found = fa.Level() == level
+			return !found
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+type indexLevel struct {
+	Level Level
+	FileName string
+	// Actions are those with a FormationAction at this Level.
+	Actions []indexAction
+	// Available counts the Actions that can be done at this
+	// Level.
+	Available int
+}
+
+type indexAction struct {
+	Action Action
+	// FormationActions are those of Action at this Level.
+	FormationActions []FormationAction
+}
+
+// WriteCatalogIndex writes an HTML file in the directory dir which
+// lists, for each of CatalogLevels, the Actions which are defined at
+// that Level and the Formations they are defined from.  Each Formation
+// links to the entry in that Level's catalog.
+func WriteCatalogIndex(dir string) error {
+	levels := []indexLevel{}
+	for _, level := range CatalogLevels {
+		il := indexLevel{
+			Level: level,
+			FileName: catalogFileName(level),
+			Actions: []indexAction{},
+			Available: len(ActionsAtOrBelow(level)),
+		}
+		for _, a := range AllActions {
+			ia := indexAction{
+				Action: a,
+				FormationActions: []FormationAction{},
+			}
+			a.DoFormationActions(func(fa FormationAction) bool {
+				if fa.Level() == level {
+					ia.FormationActions = append(ia.FormationActions, fa)
+				}
+				return true
+			})
+			if len(ia.FormationActions) > 0 {
+				sort.Slice(ia.FormationActions, func(i, j int) bool {
+					return ia.FormationActions[i].FormationType().Name() <
+						ia.FormationActions[j].FormationType().Name()
+				})
+				il.Actions = append(il.Actions, ia)
+			}
+		}
+		sort.Slice(il.Actions, func(i, j int) bool {
+			return il.Actions[i].Action.Name() < il.Actions[j].Action.Name()
+		})
+		levels = append(levels, il)
+	}
+	f, err := os.Create(filepath.Join(dir, CatalogIndexFileName))
+	if err != nil {
+		return err
+	}
+	err = index_page.Execute(f, levels)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Parameter is a slice of indexLevel.
+var index_page = template.Must(template.New("index_page").Parse(`<html>
+  <head>
+    <title>
+      Catalog of Actions by Level
+    </title>
+  </head>
+  <body>
+    <h1>
+      Catalog of Actions by Level
+    </h1>
+    <ul>
+      {{range . -}}
+        <li><a href="#{{.Level}}">{{.Level}}</a></li>
+      {{- end}}
+    </ul>
+    {{range . -}}
+      <h2 id="{{.Level}}">{{.Level}}</h2>
+      <p>
+        {{.Available}} actions can be done at this level.
+        {{if .Actions -}}
+          See also <a href="{{.FileName}}">the {{.Level}} catalog</a>.
+        {{- end}}
+      </p>
+      {{if .Actions -}}
+        <table>
+          <thead>
+            <tr>
+              <th>Action</th>
+              <th>Beats</th>
+              <th>Defined from</th>
+              <th>Description</th>
+            </tr>
+          </thead>
+          {{- $fileName := .FileName}}
+          {{range .Actions -}}
+            <tr>
+              <td>{{.Action.Name}}</td>
+              <td>{{.Action.Beats}}</td>
+              <td>
+                {{range .FormationActions -}}
+                  <a href="{{$fileName}}#{{.IdString}}">{{.FormationType.Name}}</a>
+                {{end -}}
+              </td>
+              <td>{{.Action.Description}}</td>
+            </tr>
+          {{- end}}
+        </table>
+      {{- else -}}
+        <p>No actions are defined at this level yet.</p>
+      {{- end}}
+    {{- end}}
+  </body>
+</html>
+`))
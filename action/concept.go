@@ -0,0 +1,322 @@
+// Concepts modify how an Action is performed.
+
+package action
+
+import "fmt"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/reasoning"
+import "squaredance/timeline"
+
+
+// Concept represents a square dance concept: something which changes
+// how any call is performed.  Rather than each Action defining how it
+// is done under each Concept, a Concept transforms an Action into a
+// new Action.
+type Concept interface {
+	Name() string
+	Description() string
+	Level() Level
+	// Modify returns a new Action which performs action as
+	// modified by the Concept.
+	Modify(action Action) Action
+}
+
+
+var AllConcepts []Concept = []Concept{}
+
+func FindConcept(name string) Concept {
+	for _, c := range AllConcepts {
+		if c.Name() == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func defineConcept(c Concept) {
+	if FindConcept(c.Name()) != nil {
+		panic(fmt.Sprintf("Attempt to redefine concept %s", c.Name()))
+	}
+	AllConcepts = append(AllConcepts, c)
+}
+
+
+func maxLevel(l1, l2 Level) Level {
+	if l1 > l2 {
+		return l1
+	}
+	return l2
+}
+
+// lowestLevel returns the lowest Level of any of the Action's
+// FormationActions.
+func lowestLevel(a Action) Level {
+	lowest := NotOnList
+	a.DoFormationActions(func(fa FormationAction) bool {
+		if fa.Level() < lowest {
+			lowest = fa.Level()
+		}
+		return true
+	})
+	return lowest
+}
+
+
+// superDancerConcept implements concepts like AsCouples and Tandem
+// where each small group of dancers is treated as a single dancer.
+// The groups are found by formation recognition.  The single dancer
+// of each group stands at the group's center, with the group's width
+// compressed to that of one dancer.  The Action is performed by
+// these stand in dancers and each group then follows its stand in.
+// The stand in dancers are scratch Dancers, so they are moved
+// directly rather than through the Transaction.
+type superDancerConcept struct {
+	name string
+	description string
+	level Level
+	// groupType is the FormationType of each group of dancers
+	// that is treated as a single dancer.
+	groupType reasoning.FormationType
+	// facing returns the facing direction of a group.
+	facing func(reasoning.Formation) geometry.Direction
+	// axis returns the direction along which a group's dancers
+	// are spread out.
+	axis func(reasoning.Formation) geometry.Direction
+}
+
+func (c *superDancerConcept) Name() string { return c.name }
+
+func (c *superDancerConcept) Description() string { return c.description }
+
+func (c *superDancerConcept) Level() Level { return c.level }
+
+// Modify is part of the Concept interface.  The resulting Action has
+// a single FormationAction which applies to Dancers.
+func (c *superDancerConcept) Modify(a Action) Action {
+	modified := &ActionImpl{
+		name: fmt.Sprintf("%s %s", c.Name(), a.Name()),
+		description: fmt.Sprintf("%s, each %s acting as one dancer.",
+			a.Name(), c.groupType.Name()),
+		beats: a.Beats(),
+		formationActions: []FormationAction{},
+	}
+	modified.AddFormationAction(&FormationActionImpl{
+		action: modified,
+		level: maxLevel(c.level, lowestLevel(a)),
+		formationType: reasoning.MustLookupFormationType("Dancers"),
+		doItFunc: func(f reasoning.Formation, tx *Transaction) error {
+			return c.perform(a, f, tx)
+		},
+	})
+	return modified
+}
+
+func (c *superDancerConcept) perform(a Action, f reasoning.Formation, tx *Transaction) error {
+	groups, leftover := findCover(f.Dancers(), c.groupType)
+	if len(leftover) > 0 {
+		return &ErrNotApplicable{
+			Action: c.Modify(a),
+			Formation: f,
+		}
+	}
+	center := f.Dancers().Center()
+	supers := dancer.MakeSomeDancers(len(groups))
+	for i, g := range groups {
+		supers[i].Move(
+			scaleAlong(g.Dancers().Center(), center, c.axis(g),
+				1.0 / float32(g.NumberOfDancers())),
+			c.facing(g))
+	}
+	before := supers.Copy()
+	r, err := PerformDancers(supers, a)
+	if err != nil {
+		return err
+	}
+	if !r.Complete() {
+		return &ErrNotApplicable{
+			Action: c.Modify(a),
+			Formation: f,
+		}
+	}
+	for i, g := range groups {
+		groupCenter := g.Dancers().Center()
+		rotation := supers[i].Direction().Subtract(before[i].Direction())
+		newCenter := scaleAlong(supers[i].Position(), center, c.axis(g),
+			float32(g.NumberOfDancers()))
+		for _, d := range g.Dancers() {
+			if newCenter.Equal(groupCenter) {
+				// The group turns as a unit:
+				tx.MoveAlong(timeline.NewPath(d).Arc(groupCenter, float32(rotation), 0))
+				continue
+			}
+			offset := d.Position().Subtract(groupCenter).Rotate(rotation)
+			tx.Move(d, newCenter.Add(offset), d.Direction().Add(rotation))
+		}
+	}
+	return nil
+}
+
+// scaleAlong scales the component of p's displacement from center
+// which lies along axis by factor.
+func scaleAlong(p, center geometry.Position, axis geometry.Direction, factor float32) geometry.Position {
+	unit := geometry.NewPosition(axis, 1)
+	v := p.Subtract(center)
+	along := v.Dot(unit)
+	return p.Add(unit.Scale(along * (factor - 1)))
+}
+
+
+// mirrorConcept implements concepts which perform an Action in
+// mirror image.  Copies of the dancers are reflected, perform the
+// Action, and are reflected back.  The original dancers then move to
+// where their copies ended up.
+type mirrorConcept struct {
+	name string
+	description string
+	level Level
+	// handed is true for concepts like Reverse which swap hands.
+	// Swapping hands is the same reflection, but it only makes
+	// sense if some hand is used, so such a concept doesn't apply
+	// when the mirror image ends the same way as the Action
+	// itself.
+	handed bool
+}
+
+func (c *mirrorConcept) Name() string { return c.name }
+
+func (c *mirrorConcept) Description() string { return c.description }
+
+func (c *mirrorConcept) Level() Level { return c.level }
+
+// Modify is part of the Concept interface.  The mirror image of a
+// Formation is a Formation of the same FormationType, so the
+// resulting Action has one FormationAction for each of a's.
+func (c *mirrorConcept) Modify(a Action) Action {
+	modified := &ActionImpl{
+		name: fmt.Sprintf("%s %s", c.Name(), a.Name()),
+		description: fmt.Sprintf("%s in mirror image.", a.Name()),
+		beats: a.Beats(),
+		formationActions: []FormationAction{},
+	}
+	a.DoFormationActions(func(fa FormationAction) bool {
+		base := fa
+		modified.AddFormationAction(&FormationActionImpl{
+			action: modified,
+			level: maxLevel(c.level, base.Level()),
+			formationType: base.FormationType(),
+			doItFunc: func(f reasoning.Formation, tx *Transaction) error {
+				return c.perform(base, f, tx)
+			},
+		})
+		return true
+	})
+	return modified
+}
+
+func (c *mirrorConcept) perform(fa FormationAction, f reasoning.Formation, tx *Transaction) error {
+	originals := f.Dancers()
+	center := originals.Center()
+	copies := originals.Copy()
+	reflectDancers(copies, center)
+	mirrored, leftover := findCover(copies, fa.FormationType())
+	if len(mirrored) != 1 || len(leftover) > 0 {
+		return &ErrNotApplicable{
+			Action: c.Modify(fa.Action()),
+			Formation: f,
+		}
+	}
+	if err := fa.DoIt(mirrored[0]); err != nil {
+		return err
+	}
+	reflectDancers(copies, center)
+	if c.handed {
+		same, err := sameOutcome(fa, originals, copies)
+		if err != nil {
+			return err
+		}
+		if same {
+			return &ErrNotApplicable{
+				Action: c.Modify(fa.Action()),
+				Formation: f,
+			}
+		}
+	}
+	for i, d := range originals {
+		tx.Move(d, copies[i].Position(), copies[i].Direction())
+	}
+	return nil
+}
+
+// sameOutcome returns true if doing fa from the dancers without
+// reflecting them leaves each where the corresponding one of mirrored
+// ended up.
+func sameOutcome(fa FormationAction, dancers, mirrored dancer.Dancers) (bool, error) {
+	copies := dancers.Copy()
+	found, leftover := findCover(copies, fa.FormationType())
+	if len(found) != 1 || len(leftover) > 0 {
+		return false, nil
+	}
+	if err := fa.DoIt(found[0]); err != nil {
+		return false, err
+	}
+	for i, d := range copies {
+		if !d.Position().Equal(mirrored[i].Position()) ||
+			!d.Direction().Equal(mirrored[i].Direction()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// reflectDancers reflects the dancers across the line through center
+// that runs down the hall.
+func reflectDancers(dancers dancer.Dancers, center geometry.Position) {
+	for _, d := range dancers {
+		p := d.Position()
+		d.Move(geometry.NewPositionDownLeft(p.Down, center.Left + center.Left - p.Left),
+			d.Direction().Inverse())
+	}
+}
+
+
+func init() {
+	defineConcept(&superDancerConcept{
+		name: "AsCouples",
+		description: "AsCouples treats each Couple as a single dancer.",
+		level: A_1,
+		groupType: reasoning.MustLookupFormationType("Couple"),
+		facing: func(f reasoning.Formation) geometry.Direction {
+			return f.(reasoning.Couple).Beau().Direction()
+		},
+		axis: func(f reasoning.Formation) geometry.Direction {
+			return f.(reasoning.Couple).Beau().Direction().QuarterLeft()
+		},
+	})
+	defineConcept(&superDancerConcept{
+		name: "Tandem",
+		description: "Tandem treats each Tandem as a single dancer.",
+		level: A_1,
+		groupType: reasoning.MustLookupFormationType("Tandem"),
+		facing: func(f reasoning.Formation) geometry.Direction {
+			return f.(reasoning.Tandem).Direction()
+		},
+		axis: func(f reasoning.Formation) geometry.Direction {
+			return f.(reasoning.Tandem).Direction()
+		},
+	})
+	defineConcept(&mirrorConcept{
+		name: "Mirror",
+		description: "Mirror performs the action in mirror image, " +
+			"exchanging right and left.",
+		level: C_3A,
+	})
+	defineConcept(&mirrorConcept{
+		name: "Reverse",
+		description: "Reverse performs the action with hands swapped, " +
+			"right for left and left for right.  It doesn't apply " +
+			"to actions which don't use a hand.",
+		level: NotOnList,
+		handed: true,
+	})
+}
@@ -0,0 +1,119 @@
+package action
+
+import "errors"
+import "math"
+import "testing"
+import "reflect"
+import "squaredance/timeline"
+import "squaredance/reasoning"
+
+
+func TestMirrorForwardLeft(t *testing.T) {
+	// Mirror ForwardLeft from FaceToFace should end in a
+	// LeftHanded MiniWave.
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace"))
+	tl := timeline.NewTimeline(dancers.Dancers())
+	tl.MakeSnapshot(0)
+	mirror := FindConcept("Mirror")
+	if mirror == nil {
+		t.Fatalf("No Mirror concept")
+	}
+	fa := mirror.Modify(FindAction("ForwardLeft")).GetFormationActionFor(dancers)
+	if fa == nil {
+		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
+	}
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
+	tl.MakeSnapshot(1)
+	dancers2, _ := reasoning.FindFormations(dancers.Dancers(),
+		reflect.TypeOf(func(t reasoning.MiniWave){}).In(0))
+	if want, got := 1, len(dancers2); got != want {
+		t.Errorf("Wrong number of MiniWaves: got %d, want %d.", got, want)
+		return
+	}
+	mw := dancers2[0]
+	if want, got := reasoning.LeftHanded, mw.(reasoning.MiniWave).Handedness(); got != want {
+		t.Errorf("Wrong handedness: want %v, got %v.", want, got)
+	}
+	showHistory(tl, t)
+}
+
+func TestAsCouplesQuarterRight(t *testing.T) {
+	couple := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("Couple")).(reasoning.Couple)
+	center := couple.Dancers().Center()
+	beau := couple.Beau()
+	belle := couple.Belle()
+	direction := beau.Direction()
+	action := FindConcept("AsCouples").Modify(FindAction("QuarterRight"))
+	fa := action.GetFormationActionFor(couple.Dancers())
+	if fa == nil {
+		t.Fatalf("GetFormationActionFor returned nil")
+	}
+	if err := fa.DoIt(couple.Dancers()); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
+	if got := couple.Dancers().Center(); !center.Equal(got) {
+		t.Errorf("Couple's center moved from %v to %v", center, got)
+	}
+	for _, d := range couple.Dancers() {
+		if want, got := direction.QuarterRight(), d.Direction(); !want.Equal(got) {
+			t.Errorf("Wrong direction for %s: want %v, got %v", d, want, got)
+		}
+	}
+	// The couple pivots as a unit, so the beau is still on the left:
+	if !reasoning.RightOf(beau, belle) {
+		t.Errorf("Beau %s is not left of belle %s", beau, belle)
+	}
+}
+
+func TestAsCouplesQuarterRightPaths(t *testing.T) {
+	couple := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("Couple")).(reasoning.Couple)
+	center := couple.Dancers().Center()
+	tl := timeline.NewTimeline(couple.Dancers())
+	tl.MakeSnapshot(0)
+	result, err := PerformDancers(couple.Dancers(), FindConcept("AsCouples").Modify(FindAction("QuarterRight")))
+	if err != nil {
+		t.Fatalf("PerformDancers: %s", err)
+	}
+	if want, got := 2, len(result.Paths); got != want {
+		t.Fatalf("Wrong number of paths: want %d, got %d", want, got)
+	}
+	end := timeline.RecordPaths(tl, 0, 4, result.Paths...)
+	// The dancers wheel around the couple's center rather than
+	// cutting across it:
+	for _, d := range couple.Dancers() {
+		for _, s := range tl.FindSnapshots(d, 0, end + 1) {
+			if distance := s.Position().Distance(center); math.Abs(float64(distance - 0.5)) > 0.001 {
+				t.Errorf("%s strayed from the pivot at time %d: %f", d, s.Time(), distance)
+			}
+		}
+	}
+}
+
+func TestReverse(t *testing.T) {
+	reverse := FindConcept("Reverse")
+	if reverse == nil {
+		t.Fatalf("No Reverse concept")
+	}
+	// ForwardLeft from FaceToFace ends in a RightHanded MiniWave,
+	// so Reverse ForwardLeft should end LeftHanded:
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace")).Dancers()
+	if _, err := PerformDancers(dancers, reverse.Modify(FindAction("ForwardLeft"))); err != nil {
+		t.Fatalf("PerformDancers: %s", err)
+	}
+	found, _ := reasoning.FindFormations(dancers, reasoning.MustLookupFormationType("MiniWave"))
+	if want, got := 1, len(found); got != want {
+		t.Fatalf("Wrong number of MiniWaves: want %d, got %d", want, got)
+	}
+	if want, got := reasoning.LeftHanded, found[0].(reasoning.MiniWave).Handedness(); got != want {
+		t.Errorf("Wrong handedness: want %v, got %v.", want, got)
+	}
+	// Meet doesn't use a hand, so there's nothing to reverse:
+	dancers = reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace")).Dancers()
+	_, err := PerformDancers(dancers, reverse.Modify(FindAction("Meet")))
+	var notApplicable *ErrNotApplicable
+	if !errors.As(err, &notApplicable) {
+		t.Errorf("Reverse Meet should not be applicable, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package action
+
+import "fmt"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/reasoning"
+
+
+// ErrNotApplicable is returned when a FormationAction is asked to
+// perform from a Formation that it doesn't apply to, or when an
+// Action can't be done by the dancers it was given.
+type ErrNotApplicable struct {
+	Action Action
+	// FormationAction is nil if no FormationAction of Action
+	// applied.
+	FormationAction FormationAction
+	Formation reasoning.Formation
+}
+
+func (e *ErrNotApplicable) Error() string {
+	if e.FormationAction != nil {
+		return fmt.Sprintf("%s is not allowed from %s", e.FormationAction, e.Formation)
+	}
+	return fmt.Sprintf("%s is not allowed from %s", e.Action.Name(), e.Formation)
+}
+
+
+// ErrWrongDancerCount is returned when an Action requires a specific
+// number of dancers and is given some other number.
+type ErrWrongDancerCount struct {
+	Action Action
+	Want int
+	Got int
+}
+
+func (e *ErrWrongDancerCount) Error() string {
+	return fmt.Sprintf("%s requires %d dancers, not %d",
+		e.Action.Name(), e.Want, e.Got)
+}
+
+
+// ErrConflictingMoves is returned when a Transaction would move the
+// same Dancer to two different places.  This can happen when
+// FormationActions are done from overlapping Formations.
+type ErrConflictingMoves struct {
+	Dancer dancer.Dancer
+	Position1 geometry.Position
+	Direction1 geometry.Direction
+	Position2 geometry.Position
+	Direction2 geometry.Direction
+}
+
+func (e *ErrConflictingMoves) Error() string {
+	return fmt.Sprintf("%s can't move to both %v facing %v and %v facing %v",
+		e.Dancer, e.Position1, e.Direction1, e.Position2, e.Direction2)
+}
+
+
+// ErrCollision is returned when performing an Action would cause
+// dancers to collide.
+type ErrCollision struct {
+	Action Action
+	Report *ValidationReport
+}
+
+func (e *ErrCollision) Error() string {
+	return fmt.Sprintf("%s causes collisions: %s", e.Action.Name(), e.Report)
+}
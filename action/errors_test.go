@@ -0,0 +1,47 @@
+package action
+
+import "errors"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/reasoning"
+
+
+func TestDoItNotApplicable(t *testing.T) {
+	mw := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("MiniWave"))
+	fa := FindAction("Meet").GetFormationAction(reasoning.MustLookupFormationType("FaceToFace"))
+	if fa == nil {
+		t.Fatalf("No FormationAction for Meet from FaceToFace")
+	}
+	err := fa.DoIt(mw)
+	var notApplicable *ErrNotApplicable
+	if !errors.As(err, &notApplicable) {
+		t.Fatalf("Expected ErrNotApplicable, got %v", err)
+	}
+	if notApplicable.Formation != mw {
+		t.Errorf("Wrong Formation in error: %v", notApplicable.Formation)
+	}
+}
+
+func TestTurnToFaceWrongDancerCount(t *testing.T) {
+	fa := FindAction("TurnToFace").GetFormationAction(reasoning.MustLookupFormationType("Couple"))
+	if fa == nil {
+		t.Fatalf("No FormationAction for TurnToFace from Couple")
+	}
+	// Bypass the applicability test to exercise the dancer count check:
+	err := fa.DoItFunc()(dancer.MakeSomeDancers(3), NewTransaction())
+	var wrongCount *ErrWrongDancerCount
+	if !errors.As(err, &wrongCount) {
+		t.Fatalf("Expected ErrWrongDancerCount, got %v", err)
+	}
+	if want, got := 3, wrongCount.Got; got != want {
+		t.Errorf("Wrong dancer count in error: want %d, got %d", want, got)
+	}
+}
+
+func TestLookupUnknownFormation(t *testing.T) {
+	_, err := reasoning.LookupFormationType("NoSuchFormation")
+	var unknown *reasoning.ErrUnknownFormation
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Expected ErrUnknownFormation, got %v", err)
+	}
+}
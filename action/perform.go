@@ -0,0 +1,209 @@
+// Performing an Action with all of the dancers of a set at once.
+
+package action
+
+import "fmt"
+import "reflect"
+import "sort"
+import "squaredance/dancer"
+import "squaredance/reasoning"
+import "squaredance/timeline"
+
+
+// Performance records that a FormationAction was done from a
+// specific Formation.
+type Performance struct {
+	FormationAction FormationAction
+	Formation reasoning.Formation
+}
+
+func (p Performance) String() string {
+	return fmt.Sprintf("%s from %s", p.FormationAction, p.Formation)
+}
+
+
+// Result describes what happened when an Action was performed by a
+// group of Dancers.
+type Result struct {
+	Action Action
+	// Performances lists each Formation that was recognized and
+	// the FormationAction that was done from it.
+	Performances []Performance
+	// Inactive are the Dancers that weren't designated to do the
+	// Action.
+	Inactive dancer.Dancers
+	// LeftOut are the designated Dancers that weren't in any
+	// Formation that the Action can be done from.
+	LeftOut dancer.Dancers
+	// Paths describes how each Dancer that moved got to where it
+	// ended up.
+	Paths []*timeline.Path
+	// Validation reports any collisions caused by the Action.
+	Validation *ValidationReport
+}
+
+// Complete returns true if every designated Dancer took part.
+func (r *Result) Complete() bool {
+	return len(r.LeftOut) == 0
+}
+
+
+// Perform does the Action with the Dancers of the Set.  If any
+// designators are specified then only the Dancers selected by those
+// Roles are active.
+func Perform(set dancer.Set, a Action, designators ...reasoning.Role) (*Result, error) {
+	return PerformDancers(set.Dancers(), a, designators...)
+}
+
+// PerformDancers does the Action with the specified Dancers.  The
+// active Dancers are covered by Formations that don't overlap, each
+// of which some FormationAction of the Action can be done from.
+// Larger Formations are preferred.  All of the Formations are
+// recognized before any of the dancers move.  The dancers all move
+// simultaneously.  An ErrNotApplicable is
+// returned if none of the active Dancers could do the Action.
+func PerformDancers(dancers dancer.Dancers, a Action, designators ...reasoning.Role) (*Result, error) {
+	return PerformChecked(dancers, a, ReportCollisions, designators...)
+}
+
+// PerformChecked is like PerformDancers but also checks whether the
+// dancers collide with each other.  What's done about collisions
+// depends on policy.
+func PerformChecked(dancers dancer.Dancers, a Action, policy ValidationPolicy, designators ...reasoning.Role) (*Result, error) {
+	result := &Result{
+		Action: a,
+		Performances: []Performance{},
+		Inactive: dancer.Dancers{},
+		LeftOut: dancer.Dancers{},
+	}
+	active := dancers
+	if len(designators) > 0 {
+		selected := dancer.Dancers{}
+		for _, role := range designators {
+			selected = append(selected, role.Dancers(dancers)...)
+		}
+		active = dancer.Dancers{}
+		for _, d := range dancers {
+			if selected.HasDancer(d) {
+				active = append(active, d)
+			} else {
+				result.Inactive = append(result.Inactive, d)
+			}
+		}
+	}
+	remaining := active
+	for _, fa := range byFormationSize(a) {
+		if len(remaining) == 0 {
+			break
+		}
+		cover, leftover := findCover(remaining, fa.FormationType())
+		for _, f := range cover {
+			result.Performances = append(result.Performances,
+				Performance{ FormationAction: fa, Formation: f })
+		}
+		remaining = leftover
+	}
+	result.LeftOut = remaining
+	if len(result.Performances) == 0 && len(active) > 0 {
+		return result, &ErrNotApplicable{
+			Action: a,
+			Formation: active,
+		}
+	}
+	// Every FormationAction computes from the same state of the
+	// dancers and then they all move at once.  If any
+	// FormationAction fails then nobody moves.  Each
+	// FormationAction's Paths are checked, along with those of the
+	// FormationActions before it, as soon as they're planned.
+	tx := NewTransaction()
+	result.Validation = ValidatePaths(dancers, tx.Paths())
+	for _, p := range result.Performances {
+		ptx := NewTransaction()
+		if err := p.FormationAction.Plan(p.Formation, ptx); err != nil {
+			return result, err
+		}
+		tx.Merge(ptx)
+		result.Validation = ValidatePaths(dancers, tx.Paths())
+		if !result.Validation.OK() && policy == FailOnCollision {
+			return result, &ErrCollision{
+				Action: a,
+				Report: result.Validation,
+			}
+		}
+	}
+	result.Paths = tx.Paths()
+	if result.Validation.OK() || policy == ReportCollisions {
+		return result, tx.Commit()
+	}
+	if policy == BreatheOnCollision && canBreathe(result.Validation) {
+		if err := tx.Commit(); err != nil {
+			return result, err
+		}
+		result.Validation.Breathing = Breathe(dancers)
+		return result, nil
+	}
+	return result, &ErrCollision{
+		Action: a,
+		Report: result.Validation,
+	}
+}
+
+// canBreathe returns false if any dancers would end up on the same
+// spot, since there's no way to tell which way to spread them apart.
+func canBreathe(report *ValidationReport) bool {
+	for _, c := range report.Collisions {
+		if c.Kind == SameSpot {
+			return false
+		}
+	}
+	return true
+}
+
+// byFormationSize returns the Action's FormationActions ordered so
+// that those from larger Formations come first.  Those that apply to
+// an arbitrary collection of Dancers come last.
+func byFormationSize(a Action) []FormationAction {
+	size := func(fa FormationAction) int {
+		if fa.FormationType().Kind() == reflect.Slice {
+			return 0
+		}
+		sample := reasoning.MakeSampleFormation(fa.FormationType())
+		if sample == nil {
+			return 0
+		}
+		return sample.NumberOfDancers()
+	}
+	fas := []FormationAction{}
+	a.DoFormationActions(func(fa FormationAction) bool {
+		fas = append(fas, fa)
+		return true
+	})
+	sort.SliceStable(fas, func(i, j int) bool {
+		return size(fas[i]) > size(fas[j])
+	})
+	return fas
+}
+
+
+// findCover finds Formations of the specified FormationType which
+// don't share any dancers, covering as many of the dancers as
+// possible.  It returns those Formations and the Dancers that aren't
+// in any of them.
+func findCover(dancers dancer.Dancers, ft reasoning.FormationType) ([]reasoning.Formation, dancer.Dancers) {
+	if ft.Kind() == reflect.Slice {
+		return []reasoning.Formation{ dancers }, dancer.Dancers{}
+	}
+	found, _ := reasoning.FindFormations(dancers, ft)
+	cover := reasoning.Cover(found, dancer.Dancers{})
+	covered := dancer.Dancers{}
+	for _, f := range cover {
+		covered = append(covered, f.Dancers()...)
+	}
+	leftover := dancer.Dancers{}
+	for _, d := range dancers {
+		if !covered.HasDancer(d) {
+			leftover = append(leftover, d)
+		}
+	}
+	return cover, leftover
+}
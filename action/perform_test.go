@@ -0,0 +1,94 @@
+package action
+
+import "errors"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+import "squaredance/reasoning"
+
+
+func TestPerformHeadsMeet(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	tl := timeline.NewTimeline(set.Dancers())
+	tl.MakeSnapshot(0)
+	result, err := Perform(set, FindAction("Meet"), reasoning.LookupRole("OriginalHeads"))
+	if err != nil {
+		t.Fatalf("Perform: %s", err)
+	}
+	tl.MakeSnapshot(1)
+	if !result.Complete() {
+		t.Errorf("Dancers left out: %s", result.LeftOut)
+	}
+	if want, got := 2, len(result.Performances); got != want {
+		t.Fatalf("Wrong number of Performances: want %d, got %d: %v",
+			want, got, result.Performances)
+	}
+	if want, got := 4, len(result.Inactive); got != want {
+		t.Errorf("Wrong number of inactive dancers: want %d, got %d", want, got)
+	}
+	// The sides didn't move:
+	for _, d := range result.Inactive {
+		if !tl.FindSnapshot(d, 0).Position().Equal(tl.FindSnapshot(d, 1).Position()) {
+			t.Errorf("Inactive dancer %s moved", d)
+		}
+	}
+	// The heads are close together now:
+	for _, p := range result.Performances {
+		ff := p.Formation.(reasoning.FaceToFace)
+		if distance := dancer.Distance(ff.Dancer1(), ff.Dancer2()); distance > geometry.CoupleDistance {
+			t.Errorf("Dancers are too far apart: %s, %f should be <= %f.",
+				ff, distance, geometry.CoupleDistance)
+		}
+	}
+}
+
+func TestPerformLeftOut(t *testing.T) {
+	// Nobody in a squared set is in a MiniWave:
+	set := dancer.NewSquaredSet(4)
+	result, err := Perform(set, FindAction("PassToBacks"))
+	var notApplicable *ErrNotApplicable
+	if !errors.As(err, &notApplicable) {
+		t.Errorf("Expected ErrNotApplicable, got %v", err)
+	}
+	if result.Complete() {
+		t.Errorf("PassToBacks shouldn't be possible from a squared set")
+	}
+	if want, got := 8, len(result.LeftOut); got != want {
+		t.Errorf("Wrong number of left out dancers: want %d, got %d", want, got)
+	}
+}
+
+func TestAtLevel(t *testing.T) {
+	a := approach(geometry.CoupleDistance)
+	if AtLevel(a, Primitive) != a {
+		t.Errorf("AtLevel should return an Action whose FormationActions are all at the Level")
+	}
+	// Only a Basic1 dancer could approach from a Couple:
+	a.AddFormationAction(&FormationActionImpl{
+		action: a,
+		level: Basic1,
+		formationType: reasoning.MustLookupFormationType("Couple"),
+		doItFunc: func(f reasoning.Formation, tx *Transaction) error {
+			return nil
+		},
+	})
+	restricted := AtLevel(a, Primitive)
+	if want, got := a.Name(), restricted.Name(); got != want {
+		t.Errorf("Wrong name: want %q, got %q", want, got)
+	}
+	if restricted.GetFormationAction(reasoning.MustLookupFormationType("Couple")) != nil {
+		t.Errorf("The Basic1 FormationAction wasn't removed")
+	}
+	couple := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("Couple")).Dancers()
+	if _, err := PerformDancers(couple, restricted); err == nil {
+		t.Errorf("A Couple shouldn't be able to do %s at Primitive", restricted.Name())
+	}
+	if _, err := PerformDancers(couple, AtLevel(a, Basic1)); err != nil {
+		t.Errorf("A Couple should be able to do %s at Basic1: %s", a.Name(), err)
+	}
+	ff := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace")).Dancers()
+	if _, err := PerformDancers(ff, restricted); err != nil {
+		t.Errorf("FaceToFace should still be able to do %s: %s", restricted.Name(), err)
+	}
+}
@@ -1,7 +1,6 @@
 // This file defines simple, primitive actions.
 package action
 
-import "fmt"
 import "squaredance/geometry"
 import "squaredance/dancer"
 import "squaredance/reasoning"
@@ -9,169 +8,187 @@ import "squaredance/reasoning"
 func init() {
 	// Actions which just change a Dancer's facing direction:
 
-	defineAction("QuarterRight", "QuarterRight turns the dancers one wall to the right.")
-	defineFormationAction("QuarterRight", Primitive, reasoning.LookupFormationType("Dancer"),
-		func(f reasoning.Formation) {
+	defineAction("QuarterRight", 0, "QuarterRight turns the dancers one wall to the right.")
+	defineFormationAction("QuarterRight", Primitive, reasoning.MustLookupFormationType("Dancer"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			d := f.Dancers()[0]
-			d.Move(d.Position(), d.Direction().QuarterRight())
+			tx.Move(d, d.Position(), d.Direction().QuarterRight())
+			return nil
 		})
-	defineFormationAction("QuarterRight", Primitive, reasoning.LookupFormationType("Dancers"),
-		func(f reasoning.Formation) {
+	defineFormationAction("QuarterRight", Primitive, reasoning.MustLookupFormationType("Dancers"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			for _, d := range f.Dancers() {
-				d.Move(d.Position(), d.Direction().QuarterRight())
+				tx.Move(d, d.Position(), d.Direction().QuarterRight())
 			}
+			return nil
 		})
 
-	defineAction("QuarterLeft", "QuarterLeft turns the dancers one wall to the right.")
-	defineFormationAction("QuarterLeft", Primitive, reasoning.LookupFormationType("Dancer"),
-		func(f reasoning.Formation) {
+	defineAction("QuarterLeft", 0, "QuarterLeft turns the dancers one wall to the right.")
+	defineFormationAction("QuarterLeft", Primitive, reasoning.MustLookupFormationType("Dancer"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			d := f.Dancers()[0]
-			d.Move(d.Position(), d.Direction().QuarterLeft())
+			tx.Move(d, d.Position(), d.Direction().QuarterLeft())
+			return nil
 		})
-	defineFormationAction("QuarterLeft", Primitive, reasoning.LookupFormationType("Dancers"),
-		func(f reasoning.Formation) {
+	defineFormationAction("QuarterLeft", Primitive, reasoning.MustLookupFormationType("Dancers"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			for _, d := range f.Dancers() {
-				d.Move(d.Position(), d.Direction().QuarterLeft())
+				tx.Move(d, d.Position(), d.Direction().QuarterLeft())
 			}
+			return nil
 		})
 
-	defineAction("AboutFace", "AboutFace turns the dancers around 180 degrees.")
-	defineFormationAction("AboutFace", Primitive, reasoning.LookupFormationType("Dancer"),
-		func(f reasoning.Formation) {
+	defineAction("AboutFace", 0, "AboutFace turns the dancers around 180 degrees.")
+	defineFormationAction("AboutFace", Primitive, reasoning.MustLookupFormationType("Dancer"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			d := f.Dancers()[0]
-			d.Move(d.Position(), d.Direction().Opposite())
+			tx.Move(d, d.Position(), d.Direction().Opposite())
+			return nil
 		})
-	defineFormationAction("AboutFace", Primitive, reasoning.LookupFormationType("Dancers"),
-		func(f reasoning.Formation) {
+	defineFormationAction("AboutFace", Primitive, reasoning.MustLookupFormationType("Dancers"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			for _, d := range f.Dancers() {
-				d.Move(d.Position(), d.Direction().Opposite())
+				tx.Move(d, d.Position(), d.Direction().Opposite())
 			}
+			return nil
 		})
 
 	// Fragments of Dosado, Pass Thru and other calls where Dancers
 	// approach and pass by each other:
 
-	defineAction("TurnToFace", "Two dancers turn to face each other.")
-	turnToFace := func(f reasoning.Formation) {
+	defineAction("TurnToFace", 0, "Two dancers turn to face each other.")
+	turnToFace := func(f reasoning.Formation, tx *Transaction) error {
 		dancers := f.Dancers()
 		if len(dancers) != 2 {
-			panic(fmt.Sprintf("The TurnTwoFace action requires two dancers, not %v", f))
+			return &ErrWrongDancerCount{
+				Action: FindAction("TurnToFace"),
+				Want: 2,
+				Got: len(dancers),
+			}
 		}
 		update := func(this, other dancer.Dancer) {
-			this.Move(this.Position(),
+			tx.Move(this, this.Position(),
 				this.Position().Direction(other.Position()))
 		}
 		update(dancers[0], dancers[1])
 		update(dancers[1], dancers[0])
+		return nil
 	}
-	defineFormationAction("TurnToFace", Primitive, reasoning.LookupFormationType("Couple"),
+	defineFormationAction("TurnToFace", Primitive, reasoning.MustLookupFormationType("Couple"),
 		turnToFace)
-	defineFormationAction("TurnToFace", Primitive, reasoning.LookupFormationType("MiniWave"),
+	defineFormationAction("TurnToFace", Primitive, reasoning.MustLookupFormationType("MiniWave"),
 		turnToFace)
 
-	defineAction("Meet", "Meet moves FaceToFace Dancers up to meet each other.")
-	defineFormationAction("Meet", Primitive, reasoning.LookupFormationType("FaceToFace"),
-		func(f reasoning.Formation) {
+	defineAction("Meet", 0, "Meet moves FaceToFace Dancers up to meet each other.")
+	defineFormationAction("Meet", Primitive, reasoning.MustLookupFormationType("FaceToFace"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			distance := center.Distance(f.Dancers()[0].Position()) - 
 				geometry.CoupleDistance / 2
 			update := func(d dancer.Dancer) {
 				dir := d.Position().Direction(center)
-				_ = d.Move(d.Position().Add(geometry.NewPosition(dir, distance)), d.Direction())
+				tx.Move(d, d.Position().Add(geometry.NewPosition(dir, distance)), d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
-	defineAction("ForwardLeft", "ForwardLeft moves FaceToFace dancers to a RightHanded MiniWave. This is commonly known as 'Touch'.")
-	defineFormationAction("ForwardLeft", Primitive, reasoning.LookupFormationType("FaceToFace"),
-		func(f reasoning.Formation) {
+	defineAction("ForwardLeft", 0, "ForwardLeft moves FaceToFace dancers to a RightHanded MiniWave. This is commonly known as 'Touch'.")
+	defineFormationAction("ForwardLeft", Primitive, reasoning.MustLookupFormationType("FaceToFace"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			update := func(d dancer.Dancer) {
-				d.Move(
+				tx.Move(d,
 					center.Add(geometry.NewPosition(d.Direction().QuarterLeft(),
 						geometry.CoupleDistance / 2)),
 					d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
-	defineAction("ForwardRight", "ForwardRight moves FaceToFace dancers to a LeftHanded MiniWave.  This is commonly known as 'Left Touch'.")
-	defineFormationAction("ForwardRight", Primitive, reasoning.LookupFormationType("FaceToFace"),
-		func(f reasoning.Formation) {
+	defineAction("ForwardRight", 0, "ForwardRight moves FaceToFace dancers to a LeftHanded MiniWave.  This is commonly known as 'Left Touch'.")
+	defineFormationAction("ForwardRight", Primitive, reasoning.MustLookupFormationType("FaceToFace"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			update := func(d dancer.Dancer) {
-				d.Move(
+				tx.Move(d,
 					center.Add(geometry.NewPosition(d.Direction().QuarterRight(),
 						geometry.CoupleDistance / 2)),
 					d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
-	defineAction("PassToBacks", "PassToBacks moves dancers from a MiniWave to being BackToBack.")
-	defineFormationAction("PassToBacks", Primitive, reasoning.LookupFormationType("MiniWave"),
-		func(f reasoning.Formation) {
+	defineAction("PassToBacks", 0, "PassToBacks moves dancers from a MiniWave to being BackToBack.")
+	defineFormationAction("PassToBacks", Primitive, reasoning.MustLookupFormationType("MiniWave"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			update := func(d dancer.Dancer) {
-				d.Move(
+				tx.Move(d,
 					center.Add(geometry.NewPosition(d.Direction(),
 						geometry.CoupleDistance / 2)),
 					d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
-	defineAction("BackwardLeft", "BackwardLeft moves BackToBack dancers to a RightHanded MiniWave.")
-	defineFormationAction("BackwardLeft", Primitive, reasoning.LookupFormationType("BackToBack"),
-		func(f reasoning.Formation) {
+	defineAction("BackwardLeft", 0, "BackwardLeft moves BackToBack dancers to a RightHanded MiniWave.")
+	defineFormationAction("BackwardLeft", Primitive, reasoning.MustLookupFormationType("BackToBack"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			update := func(d dancer.Dancer) {
-				d.Move(
+				tx.Move(d,
 					center.Add(geometry.NewPosition(d.Direction().QuarterLeft(),
 						geometry.CoupleDistance / 2)),
 					d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
-	defineAction("BackwardRight", "BackwardRight moves BackToBack dancers to a LeftHanded MiniWave.")
-	defineFormationAction("BackwardRight", Primitive, reasoning.LookupFormationType("BackToBack"),
-		func(f reasoning.Formation) {
+	defineAction("BackwardRight", 0, "BackwardRight moves BackToBack dancers to a LeftHanded MiniWave.")
+	defineFormationAction("BackwardRight", Primitive, reasoning.MustLookupFormationType("BackToBack"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			update := func(d dancer.Dancer) {
-				d.Move(
+				tx.Move(d,
 					center.Add(geometry.NewPosition(d.Direction().QuarterRight(),
 						geometry.CoupleDistance / 2)),
 					d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
-	defineAction("BackToFace", "BackToFace backs Dancers out of a MiniWave to face each other.")
-	defineFormationAction("BackToFace", Primitive, reasoning.LookupFormationType("MiniWave"),
-		func(f reasoning.Formation) {
+	defineAction("BackToFace", 0, "BackToFace backs Dancers out of a MiniWave to face each other.")
+	defineFormationAction("BackToFace", Primitive, reasoning.MustLookupFormationType("MiniWave"),
+		func(f reasoning.Formation, tx *Transaction) error {
 			dancers := f.Dancers()
 			center := geometry.Center(dancer.Positions(dancers...)...)
 			update := func(d dancer.Dancer) {
-				d.Move(
+				tx.Move(d,
 					center.Add(geometry.NewPosition(d.Direction().Opposite(),
 						geometry.CoupleDistance / 2)),
 					d.Direction())
 			}
 			update(dancers[0])
 			update(dancers[1])
+			return nil
 		})
 
 }
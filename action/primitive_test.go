@@ -1,15 +1,67 @@
 package action
 
+import "os"
+import "path/filepath"
 import "testing"
 import "reflect"
 import "squaredance/geometry"
 import "squaredance/dancer"
+import "squaredance/notation"
 import "squaredance/timeline"
 import "squaredance/reasoning"
 
 
-func TestWritePrimitiveCatalog(t *testing.T) {
-	WriteCatalog(Primitive)
+// TestWriteCatalogs keeps the catalogs that are checked in to this
+// directory up to date.
+func TestWriteCatalogs(t *testing.T) {
+	if err := WriteAllCatalogs("."); err != nil {
+		t.Errorf("WriteAllCatalogs: %s", err)
+	}
+}
+
+func TestWriteAllCatalogs(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteAllCatalogs(dir); err != nil {
+		t.Fatalf("WriteAllCatalogs: %s", err)
+	}
+	for _, level := range CatalogLevels {
+		_, err := os.Stat(filepath.Join(dir, catalogFileName(level)))
+		if want, got := hasFormationActions(level), err == nil; got != want {
+			t.Errorf("%s catalog written: want %v, got %v", level, want, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, CatalogIndexFileName)); err != nil {
+		t.Errorf("No index: %s", err)
+	}
+}
+
+func TestCatalogAfter(t *testing.T) {
+	fa := FindAction("ForwardLeft").GetFormationAction(
+		reasoning.MustLookupFormationType("FaceToFace"))
+	ca := newCatalogAfter(fa)
+	if ca.Err() != nil {
+		t.Fatalf("ForwardLeft: %s", ca.Err())
+	}
+	if !ca.Recognized() {
+		t.Errorf("ForwardLeft result not recognized: %v", ca.FormationNames())
+	}
+	if want, got := []string{ "RightHanded MiniWave" }, ca.FormationNames(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Wrong formations after ForwardLeft: want %v, got %v", want, got)
+	}
+	for _, fa := range UnrecognizedResults(Primitive) {
+		t.Logf("%s leaves its sample unrecognized", fa)
+	}
+}
+
+func TestActionsAtOrBelow(t *testing.T) {
+	if want, got := len(AllActions), len(ActionsAtOrBelow(C_4)); got != want {
+		t.Errorf("Every action should be allowed at C_4: want %d, got %d", want, got)
+	}
+	for _, a := range ActionsAtOrBelow(Primitive) {
+		if lowestLevel(a) != Primitive {
+			t.Errorf("%s isn't a Primitive", a.Name())
+		}
+	}
 }
 
 
@@ -26,7 +78,7 @@ func showHistory(tl timeline.Timeline, t *testing.T) {
 
 
 func TestQuarterRight(t *testing.T) {
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("Dancer"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("Dancer"))
 	dancer := dancers.Dancers()[0]
 	p := dancer.Position()
 	d := dancer.Direction()
@@ -34,7 +86,9 @@ func TestQuarterRight(t *testing.T) {
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor returned nil")
 	}
-	fa.DoIt(dancer)
+	if err := fa.DoIt(dancer); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	if !p.Equal(dancer.Position()) {
 		t.Errorf("Position changed during QuarterRight")
 	}
@@ -44,7 +98,7 @@ func TestQuarterRight(t *testing.T) {
 }
 
 func TestQuarterLeft(t *testing.T) {
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("Dancer"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("Dancer"))
 	dancer := dancers.Dancers()[0]
 	p := dancer.Position()
 	d := dancer.Direction()
@@ -52,7 +106,9 @@ func TestQuarterLeft(t *testing.T) {
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor returned nil")
 	}
-	fa.DoIt(dancer)
+	if err := fa.DoIt(dancer); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	if !p.Equal(dancer.Position()) {
 		t.Errorf("Position changed during QuarterLeft")
 	}
@@ -62,7 +118,7 @@ func TestQuarterLeft(t *testing.T) {
 }
 
 func TestAboutFace(t *testing.T) {
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("Dancer"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("Dancer"))
 	dancer := dancers.Dancers()[0]
 	p := dancer.Position()
 	d := dancer.Direction()
@@ -70,7 +126,9 @@ func TestAboutFace(t *testing.T) {
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor returned nil")
 	}
-	fa.DoIt(dancer)
+	if err := fa.DoIt(dancer); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	if !p.Equal(dancer.Position()) {
 		t.Errorf("Position changed during AboutFace")
 	}
@@ -102,7 +160,9 @@ func TestMeet(t *testing.T) {
 		if fa == nil {
 			t.Fatalf("GetFormationActionFor returned nil")
 		}
-		fa.DoIt(ff)
+		if err := fa.DoIt(ff); err != nil {
+			t.Fatalf("DoIt: %s", err)
+		}
 	}
 	tl.MakeSnapshot(1)
 	// Dancer facing directions are unchanged
@@ -142,14 +202,16 @@ func mini_wave(handedness reasoning.Handedness) reasoning.Formation {
 
 func TestForwardLeft(t *testing.T) {
 	// Start with FaceToFace dancers.  End in RightHand MiniWave
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("FaceToFace"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace"))
 	tl := timeline.NewTimeline(dancers.Dancers())
 	tl.MakeSnapshot(0)
 	fa := FindAction("ForwardLeft").GetFormationActionFor(dancers)
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
 	}
-	fa.DoIt(dancers)
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	tl.MakeSnapshot(1)
 	dancers2, _ := reasoning.FindFormations(dancers.Dancers(), reflect.TypeOf(func(t reasoning.MiniWave){}).In(0))
 	if want, got := 1, len(dancers2); got != want {
@@ -163,16 +225,36 @@ func TestForwardLeft(t *testing.T) {
 	showHistory(tl, t)
 }
 
+func TestForwardLeftDiagram(t *testing.T) {
+	dancers := notation.MustParse(`
+1v
+2u
+`)
+	result, err := PerformDancers(dancers, FindAction("ForwardLeft"))
+	if err != nil || !result.Complete() {
+		t.Fatalf("ForwardLeft: %v %v", result, err)
+	}
+	got, err := notation.Format(dancers)
+	if err != nil {
+		t.Errorf("Format: %s", err)
+	}
+	if want := "2u 1v\n"; got != want {
+		t.Errorf("Wrong result:\nwant\n%s\ngot\n%s", want, got)
+	}
+}
+
 func TestForwardRight(t *testing.T) {
 	// Start with FaceToFace dancers.  End in RightHand MiniWave
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("FaceToFace"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace"))
 	tl := timeline.NewTimeline(dancers.Dancers())
 	tl.MakeSnapshot(0)
 	fa := FindAction("ForwardRight").GetFormationActionFor(dancers)
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
 	}
-	fa.DoIt(dancers)
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	tl.MakeSnapshot(1)
 	dancers2, _ := reasoning.FindFormations(dancers.Dancers(),
 		reflect.TypeOf(func(t reasoning.MiniWave){}).In(0))
@@ -190,14 +272,16 @@ func TestForwardRight(t *testing.T) {
 func TestBackwardLeft(t *testing.T) {
 	t.Logf("TestBackwardLeft\n")
 	// Start with BackToBack dancers.  End in RightHand MiniWave
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("BackToBack"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("BackToBack"))
 	tl := timeline.NewTimeline(dancers.Dancers())
 	tl.MakeSnapshot(0)
 	fa := FindAction("BackwardLeft").GetFormationActionFor(dancers)
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
 	}
-	fa.DoIt(dancers)
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	tl.MakeSnapshot(1)
 	dancers2, _ := reasoning.FindFormations(dancers.Dancers(),
 		reflect.TypeOf(func(t reasoning.MiniWave){}).In(0))
@@ -215,14 +299,16 @@ func TestBackwardLeft(t *testing.T) {
 func TestBackwardRight(t *testing.T) {
 	t.Logf("TestBackwardRight\n")
 	// Start with BackToBack dancers.  End in RightHand MiniWave
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("BackToBack"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("BackToBack"))
 	tl := timeline.NewTimeline(dancers.Dancers())
 	tl.MakeSnapshot(0)
 	fa := FindAction("BackwardRight").GetFormationActionFor(dancers)
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
 	}
-	fa.DoIt(dancers)
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	tl.MakeSnapshot(1)
 	dancers2, _ := reasoning.FindFormations(dancers.Dancers(),
 		reflect.TypeOf(func(t reasoning.MiniWave){}).In(0))
@@ -240,14 +326,16 @@ func TestBackwardRight(t *testing.T) {
 func TestBackToFaceRight(t *testing.T) {
 	t.Logf("TestBackToFaceRight\n")
 	// Start with a RightHanded MiniWave.  End in FaceToFace dancers.
-	dancers := reasoning.MakeSampleFormation(reasoning.LookupFormationType("MiniWave"))
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("MiniWave"))
 	tl := timeline.NewTimeline(dancers.Dancers())
 	tl.MakeSnapshot(0)
 	fa := FindAction("BackToFace").GetFormationActionFor(dancers)
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
 	}
-	fa.DoIt(dancers)
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	tl.MakeSnapshot(1)
 	dancers2, _ := reasoning.FindFormations(dancers.Dancers(),
 		reflect.TypeOf(func(t reasoning.FaceToFace){}).In(0))
@@ -268,7 +356,9 @@ func TestBackToFaceLeft(t *testing.T) {
 	if fa == nil {
 		t.Fatalf("GetFormationActionFor did not find a FormationAction for %#v", dancers)
 	}
-	fa.DoIt(dancers)
+	if err := fa.DoIt(dancers); err != nil {
+		t.Fatalf("DoIt: %s", err)
+	}
 	tl.MakeSnapshot(1)
 	dancers2, _ := reasoning.FindFormations(dancers.Dancers(),
 		reflect.TypeOf(func(t reasoning.FaceToFace){}).In(0))
@@ -0,0 +1,37 @@
+// This file defines the Promenade family of calls.
+package action
+
+import "squaredance/geometry"
+import "squaredance/reasoning"
+import "squaredance/timeline"
+
+// promenade returns a function which moves the dancers the specified
+// fraction of the way around their center in promenade direction.
+func promenade(fraction float32) func(reasoning.Formation, *Transaction) error {
+	return func(f reasoning.Formation, tx *Transaction) error {
+		center := f.Dancers().Center()
+		for _, d := range f.Dancers() {
+			tx.MoveAlong(timeline.NewPath(d).Arc(center,
+				fraction * float32(geometry.FullCircle), 0))
+		}
+		return nil
+	}
+}
+
+func init() {
+	defineAction("Promenade", 0, "Promenade moves the dancers all the way around the set, back to where they started.")
+	defineFormationAction("Promenade", Basic1, reasoning.MustLookupFormationType("Dancers"),
+		promenade(1))
+
+	defineAction("PromenadeThreeQuarters", 0, "PromenadeThreeQuarters moves the dancers three quarters of the way around the set.")
+	defineFormationAction("PromenadeThreeQuarters", Basic1, reasoning.MustLookupFormationType("Dancers"),
+		promenade(0.75))
+
+	defineAction("PromenadeHalf", 0, "PromenadeHalf moves the dancers half way around the set.")
+	defineFormationAction("PromenadeHalf", Basic1, reasoning.MustLookupFormationType("Dancers"),
+		promenade(0.5))
+
+	defineAction("PromenadeQuarter", 0, "PromenadeQuarter moves the dancers a quarter of the way around the set.")
+	defineFormationAction("PromenadeQuarter", Basic1, reasoning.MustLookupFormationType("Dancers"),
+		promenade(0.25))
+}
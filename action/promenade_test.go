@@ -0,0 +1,36 @@
+package action
+
+import "testing"
+import "squaredance/dancer"
+
+
+func TestPromenade(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	dancers := set.Dancers()
+	before := dancers.Copy()
+	if _, err := PerformDancers(dancers, FindAction("Promenade")); err != nil {
+		t.Fatalf("Promenade: %s", err)
+	}
+	for i, d := range dancers {
+		if !before[i].Position().Equal(d.Position()) ||
+			!before[i].Direction().Equal(d.Direction()) {
+			t.Errorf("%s didn't get back home", d)
+		}
+	}
+	// After PromenadeHalf each dancer is where its opposite
+	// started:
+	result, err := PerformDancers(dancers, FindAction("PromenadeHalf"))
+	if err != nil {
+		t.Fatalf("PromenadeHalf: %s", err)
+	}
+	if !result.Validation.OK() {
+		t.Errorf("Unexpected collisions: %s", result.Validation)
+	}
+	center := set.FlagpoleCenter()
+	for i, d := range dancers {
+		opposite := center.Add(center.Subtract(before[i].Position()))
+		if !opposite.Equal(d.Position()) {
+			t.Errorf("%s: want %v, got %v", d, opposite, d.Position())
+		}
+	}
+}
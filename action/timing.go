@@ -0,0 +1,87 @@
+// How long Actions take.
+
+package action
+
+import "time"
+import "squaredance/timeline"
+
+
+// DefaultActionBeats is the duration of an Action whose timing isn't
+// otherwise known.
+const DefaultActionBeats = 2
+
+// standardBeats gives the standard duration, in beats, of each
+// Action.  Where an Action is a call, or part of one, the duration is
+// from the Callerlab timing tables: ForwardLeft is Touch, for example.
+// Every key is the name of a defined Action.
+var standardBeats = map[string]int{
+	"QuarterRight": 1,
+	"QuarterLeft": 1,
+	"AboutFace": 2,
+	"TurnToFace": 1,
+	"Meet": 2,
+	"ForwardLeft": 2,
+	"ForwardRight": 2,
+	"PassToBacks": 2,
+	"BackwardLeft": 2,
+	"BackwardRight": 2,
+	"BackToFace": 2,
+	"Promenade": 16,
+	"PromenadeThreeQuarters": 12,
+	"PromenadeHalf": 8,
+	"PromenadeQuarter": 4,
+}
+
+// StandardBeats returns the standard duration in beats of the named
+// Action.
+func StandardBeats(actionName string) int {
+	if beats, ok := standardBeats[actionName]; ok {
+		return beats
+	}
+	return DefaultActionBeats
+}
+
+// TotalBeats returns how many beats it takes to do all of the Actions,
+// one after the other.
+func TotalBeats(actions ...Action) int {
+	total := 0
+	for _, a := range actions {
+		total += a.Beats()
+	}
+	return total
+}
+
+
+// Tempo is the speed of the music in beats per minute.
+type Tempo float32
+
+// StandardTempo is the tempo recommended by Callerlab for Mainstream
+// and Plus dancing.
+const StandardTempo Tempo = 128
+
+// Duration returns how long the specified number of beats takes at
+// this Tempo.
+func (t Tempo) Duration(beats int) time.Duration {
+	return time.Duration(float64(beats) * float64(time.Minute) / float64(t))
+}
+
+
+// RecordResult adds DancerSnapshots to the Timeline showing the
+// dancers performing the Result's Action.  The Result's Paths are
+// stretched to take the Action's Beats and there are ticksPerBeat
+// snapshots per beat, starting after time start.  The Time at which
+// the Action finishes is returned.
+func RecordResult(tl timeline.Timeline, start timeline.Time, result *Result, ticksPerBeat int) timeline.Time {
+	beats := result.Action.Beats()
+	paths := []*timeline.Path{}
+	for _, p := range result.Paths {
+		paths = append(paths, p.Scale(float32(beats)))
+	}
+	timeline.RecordPaths(tl, start, ticksPerBeat, paths...)
+	end := start + timeline.Time(beats * ticksPerBeat)
+	// Make sure there's a snapshot at end even if nobody moved:
+	if tl.MostRecent() < end {
+		tl.MakeSnapshot(end)
+	}
+	return end
+}
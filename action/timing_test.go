@@ -0,0 +1,54 @@
+package action
+
+import "testing"
+import "time"
+import "squaredance/dancer"
+import "squaredance/timeline"
+import "squaredance/reasoning"
+
+
+func TestTempo(t *testing.T) {
+	if want, got := 30 * time.Second, StandardTempo.Duration(64); got != want {
+		t.Errorf("Wrong duration: want %v, got %v", want, got)
+	}
+	if want, got := 2, StandardBeats("ForwardLeft"); got != want {
+		t.Errorf("ForwardLeft: want %d beats, got %d", want, got)
+	}
+	if want, got := 4, TotalBeats(FindAction("Meet"), FindAction("ForwardLeft")); got != want {
+		t.Errorf("Wrong total beats: want %d, got %d", want, got)
+	}
+}
+
+func TestStandardBeatsAreActions(t *testing.T) {
+	for name, beats := range standardBeats {
+		a := FindAction(name)
+		if a == nil {
+			t.Errorf("%s has standard beats but isn't an Action", name)
+			continue
+		}
+		if want, got := beats, a.Beats(); got != want {
+			t.Errorf("%s: want %d beats, got %d", name, want, got)
+		}
+	}
+}
+
+func TestRecordResult(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	tl := timeline.NewTimeline(set.Dancers())
+	tl.MakeSnapshot(0)
+	head := set.Dancers()[0]
+	start := head.Position()
+	result, err := Perform(set, FindAction("Meet"), reasoning.LookupRole("OriginalHeads"))
+	if err != nil {
+		t.Fatalf("Perform: %s", err)
+	}
+	end := RecordResult(tl, 0, result, 2)
+	if want, got := timeline.Time(2 * FindAction("Meet").Beats()), end; got != want {
+		t.Errorf("Wrong end time: want %d, got %d", want, got)
+	}
+	// Half way through, the head is half way there:
+	halfway := start.Add(head.Position().Subtract(start).Scale(0.5))
+	if got := tl.FindSnapshot(head, end / 2).Position(); !halfway.Equal(got) {
+		t.Errorf("Wrong halfway position: want %v, got %v", halfway, got)
+	}
+}
@@ -0,0 +1,117 @@
+package action
+
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+
+
+// Transaction collects the new Positions and Directions of Dancers so
+// that they can all be changed at once.  While a Transaction is being
+// built the Dancers themselves don't move, so every FormationAction
+// computes from the same frozen state, even if the FormationActions
+// are done from overlapping Formations.
+type Transaction struct {
+	moves map[dancer.Dancer]*move
+	// order remembers the order in which Dancers were first moved
+	// so that Commit is deterministic.
+	order dancer.Dancers
+	conflicts []*ErrConflictingMoves
+}
+
+type move struct {
+	position geometry.Position
+	direction geometry.Direction
+	path *timeline.Path
+}
+
+// NewTransaction returns a new, empty Transaction.
+func NewTransaction() *Transaction {
+	return &Transaction{
+		moves: map[dancer.Dancer]*move{},
+		order: dancer.Dancers{},
+		conflicts: []*ErrConflictingMoves{},
+	}
+}
+
+// Move records that the Dancer should end up at the specified
+// Position and Direction.  The Dancer walks there in a straight line.
+// Moving the same Dancer to two different places is a conflict which
+// will be reported by Commit.
+func (tx *Transaction) Move(d dancer.Dancer, position geometry.Position, direction geometry.Direction) {
+	tx.MoveAlong(timeline.NewPath(d).Straight(position, direction, 0))
+}
+
+// MoveAlong records that the Path's Dancer should follow the Path.
+func (tx *Transaction) MoveAlong(path *timeline.Path) {
+	d := path.Dancer
+	position, direction := path.End()
+	if m, ok := tx.moves[d]; ok {
+		if !(m.position.Equal(position) && m.direction.Equal(direction)) {
+			tx.conflicts = append(tx.conflicts, &ErrConflictingMoves{
+				Dancer: d,
+				Position1: m.position,
+				Direction1: m.direction,
+				Position2: position,
+				Direction2: direction,
+			})
+		}
+		return
+	}
+	tx.moves[d] = &move{ position: position, direction: direction, path: path }
+	tx.order = append(tx.order, d)
+}
+
+// Merge records all of the moves of other in the Transaction,
+// including any conflicts among them.
+func (tx *Transaction) Merge(other *Transaction) {
+	tx.conflicts = append(tx.conflicts, other.conflicts...)
+	for _, path := range other.Paths() {
+		tx.MoveAlong(path)
+	}
+}
+
+// Moved returns the Dancers that the Transaction will move.
+func (tx *Transaction) Moved() dancer.Dancers {
+	return tx.order
+}
+
+// Position returns the Position that the Dancer will have once the
+// Transaction is committed.
+func (tx *Transaction) Position(d dancer.Dancer) geometry.Position {
+	if m, ok := tx.moves[d]; ok {
+		return m.position
+	}
+	return d.Position()
+}
+
+// Direction returns the Direction that the Dancer will have once the
+// Transaction is committed.
+func (tx *Transaction) Direction(d dancer.Dancer) geometry.Direction {
+	if m, ok := tx.moves[d]; ok {
+		return m.direction
+	}
+	return d.Direction()
+}
+
+// Paths returns the Path of each Dancer that the Transaction will
+// move.
+func (tx *Transaction) Paths() []*timeline.Path {
+	paths := []*timeline.Path{}
+	for _, d := range tx.order {
+		paths = append(paths, tx.moves[d].path)
+	}
+	return paths
+}
+
+// Commit moves all of the Dancers.  If any conflicting moves were
+// recorded then no Dancer is moved and the first conflict is returned.
+func (tx *Transaction) Commit() error {
+	if len(tx.conflicts) > 0 {
+		return tx.conflicts[0]
+	}
+	for _, d := range tx.order {
+		m := tx.moves[d]
+		d.Move(m.position, m.direction)
+	}
+	return nil
+}
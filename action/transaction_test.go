@@ -0,0 +1,72 @@
+package action
+
+import "errors"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+func TestTransactionDefersMoves(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(2)
+	dancers[0].Move(geometry.Position{ Left: geometry.Left0, Down: geometry.Down0 },
+		geometry.Direction0)
+	dancers[1].Move(geometry.Position{ Left: geometry.Left1, Down: geometry.Down0 },
+		geometry.Direction0)
+	before := dancers.Copy()
+	tx := NewTransaction()
+	// Swap the two dancers.  Each move is computed from the
+	// unchanged position of the other dancer:
+	tx.Move(dancers[0], dancers[1].Position(), dancers[0].Direction())
+	tx.Move(dancers[1], dancers[0].Position(), dancers[1].Direction())
+	for i, d := range dancers {
+		if !d.Position().Equal(before[i].Position()) {
+			t.Errorf("%s moved before Commit", d)
+		}
+	}
+	if want, got := before[1].Position(), tx.Position(dancers[0]); !want.Equal(got) {
+		t.Errorf("Pending position: want %v, got %v", want, got)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+	if want, got := before[1].Position(), dancers[0].Position(); !want.Equal(got) {
+		t.Errorf("Dancer 0: want %v, got %v", want, got)
+	}
+	if want, got := before[0].Position(), dancers[1].Position(); !want.Equal(got) {
+		t.Errorf("Dancer 1: want %v, got %v", want, got)
+	}
+}
+
+func TestTransactionConflict(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(1)
+	d := dancers[0]
+	d.Move(geometry.Origin, geometry.Direction0)
+	tx := NewTransaction()
+	tx.Move(d, geometry.Origin, geometry.Direction1)
+	// The same move again isn't a conflict:
+	tx.Move(d, geometry.Origin, geometry.Direction1)
+	tx.Move(d, geometry.Origin, geometry.Direction2)
+	err := tx.Commit()
+	var conflict *ErrConflictingMoves
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected ErrConflictingMoves, got %v", err)
+	}
+	if !d.Direction().Equal(geometry.Direction0) {
+		t.Errorf("Dancer moved despite conflict")
+	}
+}
+
+func TestTransactionMergeKeepsConflicts(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(1)
+	d := dancers[0]
+	d.Move(geometry.Origin, geometry.Direction0)
+	inner := NewTransaction()
+	inner.Move(d, geometry.Origin, geometry.Direction1)
+	inner.Move(d, geometry.Origin, geometry.Direction2)
+	tx := NewTransaction()
+	tx.Merge(inner)
+	var conflict *ErrConflictingMoves
+	if err := tx.Commit(); !errors.As(err, &conflict) {
+		t.Fatalf("Expected ErrConflictingMoves, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Checking that the dancers don't collide with each other.
+
+package action
+
+import "bytes"
+import "fmt"
+import "math"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+
+
+// OverlapDistance is how close together two dancers can be before
+// they are considered to be overlapping.
+const OverlapDistance float32 = geometry.CoupleDistance / 2
+
+
+// CollisionKind identifies what sort of problem a Collision describes.
+type CollisionKind int
+
+const (
+	// SameSpot means that two dancers ended up in the same place.
+	SameSpot CollisionKind = iota
+	// Overlap means that two dancers ended up closer together
+	// than OverlapDistance.
+	Overlap
+	// PathCrossing means that two dancers came closer together
+	// than OverlapDistance while moving, as if they had walked
+	// through each other.
+	PathCrossing
+)
+
+func (k CollisionKind) String() string {
+	switch k {
+	case SameSpot:
+		return "SameSpot"
+	case Overlap:
+		return "Overlap"
+	case PathCrossing:
+		return "PathCrossing"
+	}
+	return fmt.Sprintf("CollisionKind(%d)", int(k))
+}
+
+
+// Collision describes two dancers that got in each other's way.
+type Collision struct {
+	Kind CollisionKind
+	Dancer1 dancer.Dancer
+	Dancer2 dancer.Dancer
+	// Distance is how close together the dancers came.
+	Distance float32
+	// Where is the Dancers' midpoint when they were closest.
+	Where geometry.Position
+}
+
+func (c Collision) String() string {
+	return fmt.Sprintf("%s(%s, %s, %f at %v)",
+		c.Kind, c.Dancer1, c.Dancer2, c.Distance, c.Where)
+}
+
+
+// ValidationReport lists the Collisions that were found when checking
+// the motion of some dancers.
+type ValidationReport struct {
+	Collisions []Collision
+	// Breathing describes how the dancers were spread apart to
+	// resolve Collisions.  It is nil if they weren't.
+	Breathing *BreathingReport
+}
+
+// OK returns true if no Collisions were found.
+func (r *ValidationReport) OK() bool {
+	return len(r.Collisions) == 0
+}
+
+func (r *ValidationReport) String() string {
+	if r.OK() {
+		return "no collisions"
+	}
+	buf := bytes.NewBufferString("")
+	for i, c := range r.Collisions {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(c.String())
+	}
+	return buf.String()
+}
+
+
+// ValidationPolicy says what PerformChecked should do if the dancers
+// collide.
+type ValidationPolicy int
+
+const (
+	// ReportCollisions just records any collisions in the Result.
+	ReportCollisions ValidationPolicy = iota
+	// FailOnCollision returns an ErrCollision and leaves the
+	// dancers where they were.
+	FailOnCollision
+	// BreatheOnCollision spreads the dancers apart after they
+	// move.
+	BreatheOnCollision
+)
+
+
+// ValidationTicksPerBeat is how many times per beat ValidatePaths
+// checks where the dancers are.  Between those times each dancer is
+// taken to move in a straight line.
+const ValidationTicksPerBeat = 8
+
+// Validate checks the motion of each of the dancers from its start
+// Position to its end Position.  The dancers are assumed to move in
+// straight lines at constant speed, all starting and finishing at the
+// same time.  start and end must be parallel to dancers.
+func Validate(dancers dancer.Dancers, start, end []geometry.Position) *ValidationReport {
+	tracks := make([][]geometry.Position, len(dancers))
+	for i := range dancers {
+		tracks[i] = []geometry.Position{ start[i], end[i] }
+	}
+	return validateTracks(dancers, tracks)
+}
+
+// ValidatePaths checks the motion of the dancers as they follow the
+// Paths, all starting at the same time.  Unlike Validate it follows
+// Arcs rather than assuming that each dancer walks straight to where
+// it ends up.  Dancers without a Path stay where they are.
+func ValidatePaths(dancers dancer.Dancers, paths []*timeline.Path) *ValidationReport {
+	byDancer := map[dancer.Dancer]*timeline.Path{}
+	beats := float32(0)
+	for _, p := range paths {
+		byDancer[p.Dancer] = p
+		if b := p.Beats(); b > beats {
+			beats = b
+		}
+	}
+	ticks := int(math.Ceil(float64(beats * ValidationTicksPerBeat)))
+	if ticks < 1 {
+		ticks = 1
+	}
+	tracks := make([][]geometry.Position, len(dancers))
+	for i, d := range dancers {
+		tracks[i] = make([]geometry.Position, ticks + 1)
+		for tick := range tracks[i] {
+			if p, ok := byDancer[d]; ok {
+				tracks[i][tick], _ = p.At(beats * float32(tick) / float32(ticks))
+			} else {
+				tracks[i][tick] = d.Position()
+			}
+		}
+	}
+	return validateTracks(dancers, tracks)
+}
+
+// validateTracks checks each pair of dancers.  Each track lists the
+// Positions of the corresponding dancer at evenly spaced times.  All
+// tracks must be the same length.
+func validateTracks(dancers dancer.Dancers, tracks [][]geometry.Position) *ValidationReport {
+	report := &ValidationReport{
+		Collisions: []Collision{},
+	}
+	for i := 0; i < len(dancers); i++ {
+		for j := i + 1; j < len(dancers); j++ {
+			c, collided := checkPair(dancers[i], dancers[j], tracks[i], tracks[j])
+			if collided {
+				report.Collisions = append(report.Collisions, c)
+			}
+		}
+	}
+	return report
+}
+
+// ValidateTimeline checks the motion of the Timeline's dancers from
+// their snapshots at time start to those at time end.  Dancers that
+// lack a snapshot at either time are ignored.
+func ValidateTimeline(tl timeline.Timeline, start, end timeline.Time) *ValidationReport {
+	dancers := dancer.Dancers{}
+	starts := []geometry.Position{}
+	ends := []geometry.Position{}
+	for _, d := range tl.Dancers() {
+		s := tl.FindSnapshot(d, start)
+		e := tl.FindSnapshot(d, end)
+		if s == nil || e == nil {
+			continue
+		}
+		dancers = append(dancers, d)
+		starts = append(starts, s.Position())
+		ends = append(ends, e.Position())
+	}
+	return Validate(dancers, starts, ends)
+}
+
+func checkPair(d1, d2 dancer.Dancer, track1, track2 []geometry.Position) (Collision, bool) {
+	end1 := track1[len(track1) - 1]
+	end2 := track2[len(track2) - 1]
+	if end1.Equal(end2) {
+		return Collision{
+			Kind: SameSpot,
+			Dancer1: d1,
+			Dancer2: d2,
+			Distance: end1.Distance(end2),
+			Where: end1,
+		}, true
+	}
+	if distance := end1.Distance(end2); distance < OverlapDistance {
+		return Collision{
+			Kind: Overlap,
+			Dancer1: d1,
+			Dancer2: d2,
+			Distance: distance,
+			Where: geometry.Center(end1, end2),
+		}, true
+	}
+	// Already too close at the start isn't this call's fault:
+	if track1[0].Distance(track2[0]) < OverlapDistance {
+		return Collision{}, false
+	}
+	closest := Collision{
+		Kind: PathCrossing,
+		Dancer1: d1,
+		Dancer2: d2,
+		Distance: OverlapDistance,
+	}
+	for k := 1; k < len(track1); k++ {
+		distance, where := closestApproach(track1[k - 1], track1[k], track2[k - 1], track2[k])
+		if distance < closest.Distance {
+			closest.Distance = distance
+			closest.Where = where
+		}
+	}
+	return closest, closest.Distance < OverlapDistance
+}
+
+// closestApproach returns how close together two dancers come, and
+// their midpoint when they do, as they walk in straight lines from
+// start1 to end1 and from start2 to end2 in the same time.
+func closestApproach(start1, end1, start2, end2 geometry.Position) (float32, geometry.Position) {
+	// The dancers' separation is s0 + t * ds for t from 0 to 1.
+	s0 := start2.Subtract(start1)
+	ds := end2.Subtract(end1).Subtract(s0)
+	t := float32(0)
+	if dd := ds.Dot(ds); dd > 0 {
+		t = float32(math.Max(0, math.Min(1, float64(-s0.Dot(ds) / dd))))
+	}
+	return s0.Add(ds.Scale(t)).Magnitude(),
+		geometry.Center(
+			start1.Add(end1.Subtract(start1).Scale(t)),
+			start2.Add(end2.Subtract(start2).Scale(t)))
+}
@@ -0,0 +1,145 @@
+package action
+
+import "errors"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+import "squaredance/reasoning"
+
+
+func TestValidateCollisions(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(4)
+	p := func(down, left float32) geometry.Position {
+		return geometry.NewPositionDownLeft(geometry.Down(down), geometry.Left(left))
+	}
+	start := []geometry.Position{ p(0, 0), p(0, 2), p(3, 0), p(3, 2) }
+	end := []geometry.Position{
+		// Dancers 0 and 1 walk through each other:
+		p(0, 2), p(0, 0),
+		// Dancers 2 and 3 end up on the same spot:
+		p(3, 1), p(3, 1),
+	}
+	report := Validate(dancers, start, end)
+	if want, got := 2, len(report.Collisions); got != want {
+		t.Fatalf("Wrong number of collisions: want %d, got %d: %s", want, got, report)
+	}
+	if want, got := PathCrossing, report.Collisions[0].Kind; got != want {
+		t.Errorf("Wrong kind: want %v, got %v", want, got)
+	}
+	if want, got := p(0, 1), report.Collisions[0].Where; !want.Equal(got) {
+		t.Errorf("Wrong crossing point: want %v, got %v", want, got)
+	}
+	if want, got := SameSpot, report.Collisions[1].Kind; got != want {
+		t.Errorf("Wrong kind: want %v, got %v", want, got)
+	}
+}
+
+func TestValidatePathsArc(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(2)
+	p := func(down, left float32) geometry.Position {
+		return geometry.NewPositionDownLeft(geometry.Down(down), geometry.Left(left))
+	}
+	// Dancer 0 swings half way around p(0, 1), through where dancer
+	// 1 is standing.  Walking straight to p(0, 2) would miss it.
+	dancers[0].Move(p(0, 0), geometry.Direction0)
+	dancers[1].Move(p(1, 1), geometry.Direction0)
+	path := timeline.NewPath(dancers[0]).Arc(p(0, 1), float32(geometry.FullCircle / 2), 0)
+	end, _ := path.End()
+	if report := Validate(dancers, dancer.Positions(dancers...),
+		[]geometry.Position{ end, dancers[1].Position() }); !report.OK() {
+		t.Errorf("Unexpected collisions walking straight: %s", report)
+	}
+	report := ValidatePaths(dancers, []*timeline.Path{ path })
+	if want, got := 1, len(report.Collisions); got != want {
+		t.Fatalf("Wrong number of collisions: want %d, got %d: %s", want, got, report)
+	}
+	if want, got := PathCrossing, report.Collisions[0].Kind; got != want {
+		t.Errorf("Wrong kind: want %v, got %v", want, got)
+	}
+	// Two dancers that trade by swinging around their center never
+	// get any closer together, though walking straight they'd
+	// meet in the middle:
+	dancers[1].Move(p(0, 2), geometry.Direction2)
+	paths := []*timeline.Path{
+		timeline.NewPath(dancers[0]).Arc(p(0, 1), float32(geometry.FullCircle / 2), 0),
+		timeline.NewPath(dancers[1]).Arc(p(0, 1), float32(geometry.FullCircle / 2), 0),
+	}
+	if report := ValidatePaths(dancers, paths); !report.OK() {
+		t.Errorf("Unexpected collisions: %s", report)
+	}
+}
+
+func TestValidateTimelineHeadsMeet(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	tl := timeline.NewTimeline(set.Dancers())
+	tl.MakeSnapshot(0)
+	result, err := Perform(set, FindAction("Meet"), reasoning.LookupRole("OriginalHeads"))
+	if err != nil {
+		t.Fatalf("Perform: %s", err)
+	}
+	tl.MakeSnapshot(1)
+	if !result.Validation.OK() {
+		t.Errorf("Unexpected collisions: %s", result.Validation)
+	}
+	if report := ValidateTimeline(tl, 0, 1); !report.OK() {
+		t.Errorf("Unexpected collisions: %s", report)
+	}
+}
+
+// approach returns an Action that moves FaceToFace dancers until they
+// are separation apart.
+func approach(separation float32) Action {
+	a := &ActionImpl{
+		name: "Approach",
+		formationActions: []FormationAction{},
+	}
+	a.AddFormationAction(&FormationActionImpl{
+		action: a,
+		level: Primitive,
+		formationType: reasoning.MustLookupFormationType("FaceToFace"),
+		doItFunc: func(f reasoning.Formation, tx *Transaction) error {
+			center := f.Dancers().Center()
+			for _, d := range f.Dancers() {
+				tx.Move(d,
+					center.Add(geometry.NewPosition(center.Direction(d.Position()),
+						separation / 2)),
+					d.Direction())
+			}
+			return nil
+		},
+	})
+	return a
+}
+
+func TestPerformFailOnCollision(t *testing.T) {
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace")).Dancers()
+	before := dancer.Positions(dancers...)
+	_, err := PerformChecked(dancers, approach(0.2), FailOnCollision)
+	var collision *ErrCollision
+	if !errors.As(err, &collision) {
+		t.Fatalf("Expected ErrCollision, got %v", err)
+	}
+	if want, got := Overlap, collision.Report.Collisions[0].Kind; got != want {
+		t.Errorf("Wrong kind: want %v, got %v", want, got)
+	}
+	for i, d := range dancers {
+		if !before[i].Equal(d.Position()) {
+			t.Errorf("%s moved", d)
+		}
+	}
+}
+
+func TestPerformBreatheOnCollision(t *testing.T) {
+	dancers := reasoning.MakeSampleFormation(reasoning.MustLookupFormationType("FaceToFace")).Dancers()
+	result, err := PerformChecked(dancers, approach(0.3), BreatheOnCollision)
+	if err != nil {
+		t.Fatalf("PerformChecked: %s", err)
+	}
+	if result.Validation.Breathing == nil {
+		t.Errorf("Dancers weren't breathed")
+	}
+	if distance := dancer.Distance(dancers[0], dancers[1]); distance < geometry.CoupleDistance {
+		t.Errorf("Dancers are too close together: %f", distance)
+	}
+}
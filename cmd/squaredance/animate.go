@@ -0,0 +1,78 @@
+package main
+
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "path/filepath"
+import "strings"
+import "time"
+import "squaredance/raster"
+import "squaredance/svg"
+import "squaredance/timeline"
+
+
+// animationWriters write an animation of a Timeline in the format
+// for a file name extension.
+var animationWriters = map[string]func(w io.Writer, title string, tl timeline.Timeline, tick time.Duration) error{
+	".svg": func(w io.Writer, title string, tl timeline.Timeline, tick time.Duration) error {
+		return svg.WriteAnimation(w, tl, svg.AnimationOptions{ TickDuration: tick })
+	},
+	".html": func(w io.Writer, title string, tl timeline.Timeline, tick time.Duration) error {
+		return svg.WriteAnimationPage(w, title, tl, svg.AnimationOptions{ TickDuration: tick })
+	},
+	".gif": func(w io.Writer, title string, tl timeline.Timeline, tick time.Duration) error {
+		return raster.WriteGIF(w, tl, raster.Options{ TickDuration: tick })
+	},
+}
+
+func init() {
+	var setFile, callsFile, output *string
+	var tick *time.Duration
+	commands = append(commands, &command{
+		name: "animate",
+		usage: "[call...]",
+		summary: "Dance the calls and write an animation of them as SVG, an HTML page or a GIF.",
+		setFlags: func(flags *flag.FlagSet) {
+			setFile = flags.String("set", "", "file of the starting dancers, rather than a squared set")
+			callsFile = flags.String("f", "", "file of calls, one per line, to do after those in the arguments")
+			output = flags.String("o", "", "file to write; its extension, .svg, .html or .gif, determines the format")
+			tick = flags.Duration("tick", svg.DefaultTickDuration, "duration of each beat")
+		},
+		run: func(flags *flag.FlagSet, args []string) error {
+			if *output == "" {
+				flags.Usage()
+				return fmt.Errorf("No output file")
+			}
+			write, ok := animationWriters[strings.ToLower(filepath.Ext(*output))]
+			if !ok {
+				return fmt.Errorf("Unsupported output format %q", filepath.Ext(*output))
+			}
+			set, err := readSet(*setFile)
+			if err != nil {
+				return err
+			}
+			seq, err := readCalls(args, *callsFile)
+			if err != nil {
+				return err
+			}
+			run, err := seq.Run(set)
+			if err != nil {
+				return err
+			}
+			titles := []string{}
+			for _, call := range seq {
+				titles = append(titles, call.String())
+			}
+			out, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			err = write(out, strings.Join(titles, ", "), run.Timeline, *tick)
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			return err
+		},
+	})
+}
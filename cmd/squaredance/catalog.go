@@ -0,0 +1,29 @@
+package main
+
+import "flag"
+import "fmt"
+import "squaredance/action"
+import "squaredance/reasoning"
+
+
+func init() {
+	var dir *string
+	commands = append(commands, &command{
+		name: "catalog",
+		usage: "",
+		summary: "Write the HTML catalogs of actions and formation types.",
+		setFlags: func(flags *flag.FlagSet) {
+			dir = flags.String("dir", ".", "directory to write the catalogs in")
+		},
+		run: func(flags *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				flags.Usage()
+				return fmt.Errorf("Unexpected arguments %v", args)
+			}
+			if err := action.WriteAllCatalogs(*dir); err != nil {
+				return err
+			}
+			return reasoning.WriteFormationDiagrams(*dir)
+		},
+	})
+}
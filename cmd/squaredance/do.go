@@ -0,0 +1,52 @@
+package main
+
+import "bufio"
+import "errors"
+import "flag"
+import "fmt"
+import "os"
+import "squaredance/reasoning"
+import "squaredance/sequence"
+
+
+func init() {
+	var setFile, callsFile *string
+	commands = append(commands, &command{
+		name: "do",
+		usage: "[call...]",
+		summary: "Dance the calls and print a diagram of the dancers and their formations after each one.",
+		setFlags: func(flags *flag.FlagSet) {
+			setFile = flags.String("set", "", "file of the starting dancers, rather than a squared set")
+			callsFile = flags.String("f", "", "file of calls, one per line, to do after those in the arguments")
+		},
+		run: func(flags *flag.FlagSet, args []string) error {
+			set, err := readSet(*setFile)
+			if err != nil {
+				return err
+			}
+			seq, err := readCalls(args, *callsFile)
+			if err != nil {
+				return err
+			}
+			w := bufio.NewWriter(os.Stdout)
+			defer w.Flush()
+			dancers := set.Dancers()
+			fmt.Fprintf(w, "Start:\n")
+			writeDiagram(w, dancers)
+			writeFormations(w, reasoning.Recognize(dancers))
+			for i, call := range seq {
+				if err := (sequence.Sequence{ call }).Apply(dancers); err != nil {
+					var illegal *sequence.ErrIllegalCall
+					if errors.As(err, &illegal) {
+						illegal.Index = i
+					}
+					return err
+				}
+				fmt.Fprintf(w, "\n%d: %s\n", i + 1, call)
+				writeDiagram(w, dancers)
+				writeFormations(w, reasoning.Recognize(dancers))
+			}
+			return nil
+		},
+	})
+}
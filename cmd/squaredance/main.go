@@ -0,0 +1,187 @@
+// Command squaredance recognizes formations, dances calls and writes
+// catalogs and animations from the command line.
+//
+// Usage:
+//
+//	squaredance command [flags] [arguments]
+//
+// Run "squaredance help" for the list of commands.  Sets of dancers
+// are read either as diagrams in the notation of package notation or
+// as JSON as written by package dancer.  A file name of "-" means
+// standard input.
+package main
+
+import "bufio"
+import "bytes"
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "strings"
+import "squaredance/dancer"
+import "squaredance/notation"
+import "squaredance/reasoning"
+import "squaredance/sd"
+import "squaredance/sequence"
+
+
+// command is one of the subcommands of squaredance.
+type command struct {
+	name string
+	// usage describes the arguments, after any flags.
+	usage string
+	summary string
+	// run is called with the arguments which follow the command
+	// name.
+	run func(flags *flag.FlagSet, args []string) error
+	// setFlags defines the command's flags.
+	setFlags func(flags *flag.FlagSet)
+}
+
+// commands are in the order they're listed by help.  Each file adds
+// its own in an init function.
+var commands = []*command{}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *command) flagSet() *flag.FlagSet {
+	flags := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	if c.setFlags != nil {
+		c.setFlags(flags)
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags] %s\n%s\n",
+			os.Args[0], c.name, c.usage, c.summary)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+var Usage = func() {
+	fmt.Fprintf(os.Stderr, "Usage: %s command [flags] [arguments]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "Use \"%s command -h\" for help with a command.\n", os.Args[0])
+}
+
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" {
+		Usage()
+		if len(os.Args) < 2 {
+			os.Exit(2)
+		}
+		return
+	}
+	c := findCommand(os.Args[1])
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", os.Args[1])
+		Usage()
+		os.Exit(2)
+	}
+	flags := c.flagSet()
+	if err := flags.Parse(os.Args[2:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
+	}
+	if err := c.run(flags, flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %s\n", os.Args[0], c.name, err)
+		os.Exit(1)
+	}
+}
+
+
+// readInput returns the contents of the named file, or of standard
+// input if the name is "-".
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// readSet returns a Set of the dancers in the named file, or a
+// squared set of four couples if name is empty.
+func readSet(name string) (dancer.Set, error) {
+	if name == "" {
+		return dancer.NewSquaredSet(4), nil
+	}
+	data, err := readInput(name)
+	if err != nil {
+		return nil, err
+	}
+	dancers, err := notation.ParseDancers(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return dancer.NewSet(dancers), nil
+}
+
+
+// readCalls returns the Sequence of the calls in the arguments, one
+// per argument, followed by those in the named file, one per line, if
+// name isn't empty.  Blank lines and lines starting with "#" are
+// ignored.
+func readCalls(args []string, name string) (sequence.Sequence, error) {
+	texts := append([]string{}, args...)
+	if name != "" {
+		data, err := readInput(name)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			texts = append(texts, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	seq := sequence.Sequence{}
+	for _, text := range texts {
+		call, err := sd.ParseAnyCall(text)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, call)
+	}
+	return seq, nil
+}
+
+
+// writeDiagram writes a diagram of the dancers.  If it's inexact then
+// why is written after it.
+func writeDiagram(w io.Writer, dancers dancer.Dancers) {
+	diagram, err := notation.Format(dancers)
+	io.WriteString(w, diagram)
+	if err != nil {
+		fmt.Fprintf(w, "%s\n", err)
+	}
+}
+
+// writeFormations writes a line for each of the Formations naming it
+// and its dancers.
+func writeFormations(w io.Writer, formations []reasoning.Formation) {
+	for _, f := range formations {
+		names := []string{}
+		for _, d := range f.Dancers() {
+			names = append(names, d.String())
+		}
+		fmt.Fprintf(w, "%s: %s\n", reasoning.FormationName(f), strings.Join(names, " "))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import "os"
+import "path/filepath"
+import "testing"
+
+
+func TestReadCalls(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "calls")
+	if err := os.WriteFile(file, []byte("# Some calls\nOriginalHeads QuarterLeft\n\nsides quarter right\n"), 0644); err != nil {
+		t.Fatalf("%s", err)
+	}
+	seq, err := readCalls([]string{ "heads start meet" }, file)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := []string{ "OriginalHeads Meet", "OriginalHeads QuarterLeft", "OriginalSides QuarterRight" }
+	if len(seq) != len(want) {
+		t.Fatalf("Wrong calls: want %v, got %v", want, seq)
+	}
+	for i, call := range seq {
+		if got := call.String(); got != want[i] {
+			t.Errorf("Wrong call %d: want %q, got %q", i, want[i], got)
+		}
+	}
+	if _, err := readCalls([]string{ "frobnicate" }, ""); err == nil {
+		t.Errorf("Expected an error for an unknown call")
+	}
+}
+
+func TestAnimate(t *testing.T) {
+	c := findCommand("animate")
+	for _, ext := range []string{ ".svg", ".html", ".gif" } {
+		output := filepath.Join(t.TempDir(), "animation" + ext)
+		flags := c.flagSet()
+		if err := flags.Parse([]string{ "-o", output, "heads meet" }); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if err := c.run(flags, flags.Args()); err != nil {
+			t.Fatalf("%s", err)
+		}
+		info, err := os.Stat(output)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if info.Size() == 0 {
+			t.Errorf("%s is empty", output)
+		}
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	c := findCommand("catalog")
+	flags := c.flagSet()
+	if err := flags.Parse([]string{ "-dir", dir }); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := c.run(flags, flags.Args()); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, name := range []string{ "catalog.html", "catalog-Primitive.html", "formation_types.html" } {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s", err)
+		}
+	}
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("The working directory changed from %s to %s", wd, now)
+	}
+}
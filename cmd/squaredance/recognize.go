@@ -0,0 +1,50 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "squaredance/notation"
+import "squaredance/reasoning"
+
+
+func init() {
+	var asJSON *bool
+	commands = append(commands, &command{
+		name: "recognize",
+		usage: "[file]",
+		summary: "Print the formations that the dancers in the file, or standard input, are in.",
+		setFlags: func(flags *flag.FlagSet) {
+			asJSON = flags.Bool("json", false, "print the formations as JSON")
+		},
+		run: func(flags *flag.FlagSet, args []string) error {
+			name := "-"
+			switch len(args) {
+			case 0:
+			case 1:
+				name = args[0]
+			default:
+				flags.Usage()
+				return fmt.Errorf("Too many arguments")
+			}
+			data, err := readInput(name)
+			if err != nil {
+				return err
+			}
+			dancers, err := notation.ParseDancers(data)
+			if err != nil {
+				return err
+			}
+			formations := reasoning.Recognize(dancers)
+			if *asJSON {
+				encoded, err := reasoning.MarshalFormations(formations)
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintf(os.Stdout, "%s\n", encoded)
+				return err
+			}
+			writeFormations(os.Stdout, formations)
+			return nil
+		},
+	})
+}
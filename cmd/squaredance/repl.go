@@ -0,0 +1,38 @@
+package main
+
+import "flag"
+import "fmt"
+import "os"
+import "squaredance/repl"
+
+
+func init() {
+	var sessionFile *string
+	commands = append(commands, &command{
+		name: "repl",
+		usage: "",
+		summary: "Practice choreography: do calls one at a time, with undo, redo and saving.",
+		setFlags: func(flags *flag.FlagSet) {
+			sessionFile = flags.String("f", "", "file of calls, as saved by the save command, to start with")
+		},
+		run: func(flags *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				flags.Usage()
+				return fmt.Errorf("Unexpected arguments %v", args)
+			}
+			s := repl.NewSession()
+			if *sessionFile != "" {
+				f, err := os.Open(*sessionFile)
+				if err != nil {
+					return err
+				}
+				err = s.Load(f)
+				f.Close()
+				if err != nil {
+					return err
+				}
+			}
+			return s.Run()
+		},
+	})
+}
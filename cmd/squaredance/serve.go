@@ -0,0 +1,28 @@
+package main
+
+import "flag"
+import "fmt"
+import "log"
+import "net/http"
+import "squaredance/server"
+
+
+func init() {
+	var addr *string
+	commands = append(commands, &command{
+		name: "serve",
+		usage: "",
+		summary: "Serve the HTTP API of package server.",
+		setFlags: func(flags *flag.FlagSet) {
+			addr = flags.String("addr", "localhost:8080", "address to listen on")
+		},
+		run: func(flags *flag.FlagSet, args []string) error {
+			if len(args) > 0 {
+				flags.Usage()
+				return fmt.Errorf("Unexpected arguments %v", args)
+			}
+			log.Printf("Listening on %s", *addr)
+			return http.ListenAndServe(*addr, server.NewServer())
+		},
+	})
+}
@@ -28,7 +28,7 @@ func (g Gender) String() string {
 	case Gal:
 		return "Gal"
 	}
-	panic(fmt.Sprintf("Unsupported Gender %d", int(g)))
+	return fmt.Sprintf("Gender(%d)", int(g))
 }
 
 // Equal returns true if the Genders are the same.
@@ -198,6 +198,8 @@ func (d1 *DancerImpl) GoshuaEqual(d2 interface{}) (bool, error) {
 type Set interface {
 	FlagpoleCenter() geometry.Position   // defimpl:"read flagpoleCenter"
 	Dancers()        Dancers              // defimpl:"read dancers"
+	// Resolve analyzes how close the Set is to being resolved.
+	Resolve() *Resolution
 	// Support the reasoning/Formation interface:
 	NumberOfDancers() int
 	HasDancer(d Dancer) bool
@@ -256,6 +258,30 @@ func NewSquaredSet(couples int) Set {
 	return &s
 }
 
+// NewSet returns a new Set of copies of the Dancers, such as those
+// read from a diagram, with its FlagpoleCenter at geometry.Origin.
+func NewSet(dancers Dancers) Set {
+	s := &SetImpl{
+		flagpoleCenter: geometry.Origin,
+		dancers:        dancers.Copy(),
+	}
+	for _, d := range s.dancers {
+		d.(*DancerImpl).set = s
+	}
+	return s
+}
+
+// NewDancer returns a new Dancer, not in any Set, with the specified
+// Ordinal, CoupleNumber and Gender.
+func NewDancer(ordinal int, coupleNumber int, gender Gender) Dancer {
+	return &DancerImpl{
+		set:          nil,
+		ordinal:      ordinal,
+		gender:       gender,
+		coupleNumber: coupleNumber,
+	}
+}
+
 // MakeSomeDancers returns the specified number of Gender neutral Dancers.
 func MakeSomeDancers(count int) Dancers {
 	dancers := Dancers{}
@@ -271,6 +297,35 @@ func MakeSomeDancers(count int) Dancers {
 }
 
 
+// Copy returns new Dancers with the same Ordinal, CoupleNumber,
+// Gender, Position and Direction as those in ds.  The copies don't
+// belong to any Set.  OriginalPartner is remapped to the copy of the
+// partner if the partner is also being copied.
+func (ds Dancers) Copy() Dancers {
+	copies := make(Dancers, len(ds))
+	mapping := map[Dancer]Dancer{}
+	for i, d := range ds {
+		copies[i] = &DancerImpl{
+			set:          nil,
+			ordinal:      d.Ordinal(),
+			gender:       d.Gender(),
+			coupleNumber: d.CoupleNumber(),
+			position:     d.Position(),
+			direction:    d.Direction(),
+		}
+		mapping[d] = copies[i]
+	}
+	for i, d := range ds {
+		if p, ok := mapping[d.OriginalPartner()]; ok {
+			copies[i].SetOriginalPartner(p)
+		} else {
+			copies[i].SetOriginalPartner(d.OriginalPartner())
+		}
+	}
+	return copies
+}
+
+
 // Positions returns the Position of each Dancer
 func Positions(dancers ...Dancer) []geometry.Position {
 	length := len(dancers)
@@ -44,6 +44,25 @@ func TestSquaredSet(t *testing.T) {
 	}
 }
 
+func TestNewSet(t *testing.T) {
+	dancers := NewSquaredSet(4).Dancers()
+	s := NewSet(dancers)
+	if want, got := len(dancers), s.NumberOfDancers(); got != want {
+		t.Fatalf("Wrong number of dancers: want %d, got %d", want, got)
+	}
+	for i, d := range s.Dancers() {
+		if d == dancers[i] {
+			t.Errorf("Dancer %d wasn't copied", i)
+		}
+		if s != d.Set() {
+			t.Errorf("Dancer's Set is wrong, %d", i)
+		}
+		if d.OriginalPartner().Set() != s {
+			t.Errorf("Dancer's OriginalPartner isn't in the Set, %d", i)
+		}
+	}
+}
+
 func TestUnion(t *testing.T) {
 	s := NewSquaredSet(4)
 	dancers1 := s.Dancers()[1:4]
@@ -0,0 +1,78 @@
+// Identifying arrangements of dancers.
+
+package dancer
+
+import "bytes"
+import "fmt"
+import "math"
+import "sort"
+import "squaredance/geometry"
+
+
+// fingerprintGrid is the spacing that positions are rounded to when
+// computing a Fingerprint.
+const fingerprintGrid = geometry.CoupleDistance / 2
+
+// fingerprintDirections is how many directions facing directions are
+// rounded to when computing a Fingerprint.
+const fingerprintDirections = 8
+
+type fingerprintEntry struct {
+	ordinal int
+	down, left int
+	direction int
+}
+
+func (ds Dancers) fingerprintEntries(center geometry.Position) []fingerprintEntry {
+	entries := []fingerprintEntry{}
+	for _, d := range ds {
+		p := d.Position().Subtract(center)
+		direction := int(math.Round(float64(d.Direction()) * fingerprintDirections))
+		entries = append(entries, fingerprintEntry{
+			ordinal: d.Ordinal(),
+			down: int(math.Round(float64(float32(p.Down) / fingerprintGrid))),
+			left: int(math.Round(float64(float32(p.Left) / fingerprintGrid))),
+			direction: (direction % fingerprintDirections + fingerprintDirections) % fingerprintDirections,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ordinal < entries[j].ordinal
+	})
+	return entries
+}
+
+func fingerprintString(entries []fingerprintEntry) string {
+	buf := bytes.NewBufferString("")
+	for _, e := range entries {
+		fmt.Fprintf(buf, "%d:%d,%d,%d;", e.ordinal, e.down, e.left, e.direction)
+	}
+	return buf.String()
+}
+
+// Fingerprint returns a string which identifies where each of the
+// dancers is, relative to center, and which way it's facing.
+// Positions are rounded to half of CoupleDistance and directions to
+// an eighth of a circle, so dancers that are a little out of place
+// still have the same Fingerprint.
+func (ds Dancers) Fingerprint(center geometry.Position) string {
+	return fingerprintString(ds.fingerprintEntries(center))
+}
+
+// CanonicalFingerprint is like Fingerprint except that arrangements
+// that differ only by a rotation of the whole set by some number of
+// quarter turns around center have the same CanonicalFingerprint.
+func (ds Dancers) CanonicalFingerprint(center geometry.Position) string {
+	entries := ds.fingerprintEntries(center)
+	best := ""
+	for quarter := 0; quarter < 4; quarter++ {
+		if s := fingerprintString(entries); quarter == 0 || s < best {
+			best = s
+		}
+		// Rotate by a quarter turn in promenade direction:
+		for i, e := range entries {
+			entries[i].down, entries[i].left = -e.left, e.down
+			entries[i].direction = (e.direction + fingerprintDirections / 4) % fingerprintDirections
+		}
+	}
+	return best
+}
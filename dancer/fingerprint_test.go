@@ -0,0 +1,26 @@
+package dancer
+
+import "testing"
+import "squaredance/geometry"
+
+
+func TestCanonicalFingerprint(t *testing.T) {
+	set := NewSquaredSet(4)
+	center := set.FlagpoleCenter()
+	before := set.Dancers().Fingerprint(center)
+	canonical := set.Dancers().CanonicalFingerprint(center)
+	for _, d := range set.Dancers() {
+		d.Move(d.Position().Rotate(geometry.Direction1), d.Direction().QuarterLeft())
+	}
+	if set.Dancers().Fingerprint(center) == before {
+		t.Errorf("Fingerprint didn't change when the set was rotated")
+	}
+	if got := set.Dancers().CanonicalFingerprint(center); got != canonical {
+		t.Errorf("CanonicalFingerprint changed: %s, %s", canonical, got)
+	}
+	// Turning one dancer around changes things:
+	set.Dancers()[0].Rotate(geometry.Direction2)
+	if got := set.Dancers().CanonicalFingerprint(center); got == canonical {
+		t.Errorf("CanonicalFingerprint should have changed")
+	}
+}
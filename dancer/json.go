@@ -0,0 +1,203 @@
+// Saving and loading Dancers and Sets as JSON.
+
+package dancer
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "squaredance/geometry"
+
+
+// JSONVersion is the version of the JSON encoding of Dancers, Sets,
+// Formations and Timelines.  It is written in the "version" field of
+// each top level JSON object and checked when decoding.
+const JSONVersion = 1
+
+// CheckJSONVersion returns an error if version isn't one that can be
+// decoded.
+func CheckJSONVersion(version int) error {
+	if version != JSONVersion {
+		return fmt.Errorf("Unsupported JSON version %d, expected %d", version, JSONVersion)
+	}
+	return nil
+}
+
+// ParseGender returns the Gender whose String is name.
+func ParseGender(name string) (Gender, error) {
+	for _, g := range []Gender{ Unspecified, Guy, Gal } {
+		if g.String() == name {
+			return g, nil
+		}
+	}
+	return Unspecified, fmt.Errorf("Unknown gender %q", name)
+}
+
+
+// DancerJSON is the JSON encoding of a Dancer.  Partner is the Ordinal
+// of the Dancer's OriginalPartner, if it has one.
+type DancerJSON struct {
+	Ordinal int                  `json:"ordinal"`
+	CoupleNumber int             `json:"couple"`
+	Gender string                `json:"gender"`
+	Down float32                 `json:"down"`
+	Left float32                 `json:"left"`
+	Direction float32            `json:"direction"`
+	Partner *int                 `json:"partner,omitempty"`
+}
+
+// NewDancerJSON returns the JSON encoding of the Dancer.
+func NewDancerJSON(d Dancer) DancerJSON {
+	dj := DancerJSON{
+		Ordinal: d.Ordinal(),
+		CoupleNumber: d.CoupleNumber(),
+		Gender: d.Gender().String(),
+		Down: float32(d.Position().Down),
+		Left: float32(d.Position().Left),
+		Direction: float32(d.Direction()),
+	}
+	if p := d.OriginalPartner(); p != nil {
+		ordinal := p.Ordinal()
+		dj.Partner = &ordinal
+	}
+	return dj
+}
+
+// MarshalJSON encodes the Dancer as a DancerJSON.
+func (d *DancerImpl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewDancerJSON(d))
+}
+
+// NewDancersJSON returns the JSON encodings of the Dancers.
+func NewDancersJSON(ds Dancers) []DancerJSON {
+	result := []DancerJSON{}
+	for _, d := range ds {
+		result = append(result, NewDancerJSON(d))
+	}
+	return result
+}
+
+// DancersFromJSON makes new Dancers, not in any Set, from their JSON
+// encodings.  Partner references are resolved among them.
+func DancersFromJSON(encoded []DancerJSON) (Dancers, error) {
+	return dancersFromJSON(encoded, nil)
+}
+
+func dancersFromJSON(encoded []DancerJSON, set Set) (Dancers, error) {
+	dancers := Dancers{}
+	byOrdinal := map[int]Dancer{}
+	for _, dj := range encoded {
+		gender, err := ParseGender(dj.Gender)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byOrdinal[dj.Ordinal]; ok {
+			return nil, fmt.Errorf("Duplicate dancer ordinal %d", dj.Ordinal)
+		}
+		d := &DancerImpl{
+			set:          set,
+			ordinal:      dj.Ordinal,
+			gender:       gender,
+			coupleNumber: dj.CoupleNumber,
+			position:     geometry.NewPositionDownLeft(
+				geometry.Down(dj.Down), geometry.Left(dj.Left)),
+			direction:    geometry.Direction(dj.Direction),
+		}
+		byOrdinal[dj.Ordinal] = d
+		dancers = append(dancers, d)
+	}
+	for i, dj := range encoded {
+		if dj.Partner == nil {
+			continue
+		}
+		partner, ok := byOrdinal[*dj.Partner]
+		if !ok {
+			return nil, fmt.Errorf("Dancer %d has unknown partner %d",
+				dj.Ordinal, *dj.Partner)
+		}
+		dancers[i].SetOriginalPartner(partner)
+	}
+	return dancers, nil
+}
+
+// DancersJSON is the JSON encoding of Dancers that aren't in a Set.
+type DancersJSON struct {
+	Version int                  `json:"version"`
+	Dancers []DancerJSON         `json:"dancers"`
+}
+
+// MarshalJSON encodes the Dancers as a DancersJSON.
+func (ds Dancers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(DancersJSON{
+		Version: JSONVersion,
+		Dancers: NewDancersJSON(ds),
+	})
+}
+
+// UnmarshalJSON decodes Dancers that were encoded by MarshalJSON.
+// The Dancers don't belong to any Set.  A bare array of DancerJSON,
+// which has no version, isn't accepted.
+func (ds *Dancers) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return fmt.Errorf("Dancers must be encoded as an object with a version, not an array")
+	}
+	dj := DancersJSON{}
+	if err := json.Unmarshal(data, &dj); err != nil {
+		return err
+	}
+	if err := CheckJSONVersion(dj.Version); err != nil {
+		return err
+	}
+	dancers, err := DancersFromJSON(dj.Dancers)
+	if err != nil {
+		return err
+	}
+	*ds = dancers
+	return nil
+}
+
+
+// SetJSON is the JSON encoding of a Set.
+type SetJSON struct {
+	Version int                  `json:"version"`
+	CenterDown float32           `json:"centerDown"`
+	CenterLeft float32           `json:"centerLeft"`
+	Dancers []DancerJSON         `json:"dancers"`
+}
+
+// MarshalJSON encodes the Set as a SetJSON.
+func (s *SetImpl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(SetJSON{
+		Version: JSONVersion,
+		CenterDown: float32(s.FlagpoleCenter().Down),
+		CenterLeft: float32(s.FlagpoleCenter().Left),
+		Dancers: NewDancersJSON(s.Dancers()),
+	})
+}
+
+// UnmarshalJSON decodes a Set that was encoded by MarshalJSON.
+func (s *SetImpl) UnmarshalJSON(data []byte) error {
+	sj := SetJSON{}
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	if err := CheckJSONVersion(sj.Version); err != nil {
+		return err
+	}
+	dancers, err := dancersFromJSON(sj.Dancers, s)
+	if err != nil {
+		return err
+	}
+	s.flagpoleCenter = geometry.NewPositionDownLeft(
+		geometry.Down(sj.CenterDown), geometry.Left(sj.CenterLeft))
+	s.dancers = dancers
+	return nil
+}
+
+// UnmarshalSet returns the Set encoded in data.
+func UnmarshalSet(data []byte) (Set, error) {
+	s := &SetImpl{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,66 @@
+package dancer
+
+import "encoding/json"
+import "strings"
+import "testing"
+
+
+func TestSetJSONRoundTrip(t *testing.T) {
+	set := NewSquaredSet(4)
+	set.Dancers()[3].Rotate(0.25)
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	if !strings.Contains(string(data), `"version":1`) {
+		t.Errorf("No version: %s", data)
+	}
+	decoded, err := UnmarshalSet(data)
+	if err != nil {
+		t.Fatalf("UnmarshalSet: %s", err)
+	}
+	if !decoded.FlagpoleCenter().Equal(set.FlagpoleCenter()) {
+		t.Errorf("Wrong center: %v", decoded.FlagpoleCenter())
+	}
+	for i, want := range set.Dancers() {
+		got := decoded.Dancers()[i]
+		if got.Ordinal() != want.Ordinal() ||
+			got.CoupleNumber() != want.CoupleNumber() ||
+			got.Gender() != want.Gender() ||
+			!got.Position().Equal(want.Position()) ||
+			!got.Direction().Equal(want.Direction()) {
+			t.Errorf("Dancer %d: want %v, got %v", i, want, got)
+		}
+		if got.OriginalPartner().Ordinal() != want.OriginalPartner().Ordinal() {
+			t.Errorf("Wrong partner for %v", got)
+		}
+		if got.Set() != decoded {
+			t.Errorf("%v isn't in the decoded Set", got)
+		}
+	}
+}
+
+func TestDancersJSON(t *testing.T) {
+	dancers := MakeSomeDancers(2)
+	data, err := json.Marshal(dancers)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	decoded := Dancers{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+	if len(decoded) != 2 || decoded[1].Gender() != Unspecified ||
+		decoded[0].OriginalPartner() != nil {
+		t.Errorf("Bad decoding %s: %v", data, decoded)
+	}
+	if _, err := UnmarshalSet([]byte(`{"version":99,"dancers":[]}`)); err == nil {
+		t.Errorf("Wrong version should fail")
+	}
+	if err := json.Unmarshal([]byte(`{"version":99,"dancers":[]}`), &decoded); err == nil {
+		t.Errorf("Wrong version of Dancers should fail")
+	}
+	if err := json.Unmarshal([]byte(`[]`), &decoded); err == nil {
+		t.Errorf("Dancers without a version should fail")
+	}
+}
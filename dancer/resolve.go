@@ -0,0 +1,241 @@
+// Determining whether the dancers of a set are resolved.
+
+package dancer
+
+import "fmt"
+import "math"
+import "squaredance/geometry"
+
+
+// ResolvePoint identifies a position from which the caller can bring
+// the dancers home with a standard get out.
+type ResolvePoint int
+
+const (
+	NotResolved ResolvePoint = iota
+	// HomePoint means everyone is at home.
+	HomePoint
+	// PromenadePoint means everyone is promenading with their
+	// partner, in sequence.
+	PromenadePoint
+	// RightAndLeftGrandPoint means partners are facing each other,
+	// the guys facing promenade direction, in sequence.
+	RightAndLeftGrandPoint
+	// AllemandeLeftPoint means each guy has his corner on his left
+	// and his partner on his right, in sequence.
+	AllemandeLeftPoint
+)
+
+func (p ResolvePoint) String() string {
+	switch p {
+	case NotResolved:
+		return "NotResolved"
+	case HomePoint:
+		return "Home"
+	case PromenadePoint:
+		return "Promenade"
+	case RightAndLeftGrandPoint:
+		return "RightAndLeftGrand"
+	case AllemandeLeftPoint:
+		return "AllemandeLeft"
+	}
+	return fmt.Sprintf("ResolvePoint(%d)", int(p))
+}
+
+// resolveCalls are the calls that get the dancers home from each
+// ResolvePoint, written the way a caller would say them.  They are
+// advice for people, not Action names: most of these calls aren't
+// defined as Actions and can't be danced.
+var resolveCalls = map[ResolvePoint][]string{
+	HomePoint: []string{},
+	PromenadePoint: []string{ "Promenade" },
+	RightAndLeftGrandPoint: []string{ "Right and Left Grand", "Promenade" },
+	AllemandeLeftPoint: []string{ "Allemande Left", "Right and Left Grand", "Promenade" },
+}
+
+
+// Resolution describes how close the dancers of a Set are to being
+// resolved.
+type Resolution struct {
+	// Paired counts the couples where the partners are side by
+	// side, facing the same way, with the guy on the left.
+	Paired int
+	// Unpaired lists the dancers who aren't beside their
+	// OriginalPartner.
+	Unpaired Dancers
+	// InSequence is true if the guys are in couple number order
+	// counterclockwise around the set, as are the gals, and every
+	// gal is the same way around from her partner.
+	InSequence bool
+	Point ResolvePoint
+	// Calls tells a caller which calls would get everyone home.
+	// It's advisory text rather than calls that can be parsed and
+	// danced.  It is nil if the Set isn't at a ResolvePoint.
+	Calls []string
+}
+
+// Resolved returns true if the dancers are at a ResolvePoint.
+func (r *Resolution) Resolved() bool {
+	return r.Point != NotResolved
+}
+
+func (r *Resolution) String() string {
+	return fmt.Sprintf("%s, %d couples paired, in sequence: %v, calls: %v",
+		r.Point, r.Paired, r.InSequence, r.Calls)
+}
+
+
+// Resolve analyzes how close the dancers of the Set are to being
+// resolved.
+func (s *SetImpl) Resolve() *Resolution {
+	return ResolveDancers(s.Dancers(), s.FlagpoleCenter())
+}
+
+// ResolveDancers analyzes how close the dancers, which should be
+// those of a squared set with the specified center, are to being
+// resolved.
+func ResolveDancers(dancers Dancers, center geometry.Position) *Resolution {
+	r := &Resolution{
+		Unpaired: Dancers{},
+	}
+	couples := len(dancers) / 2
+	guys := make(Dancers, couples + 1)
+	gals := make(Dancers, couples + 1)
+	for _, d := range dancers {
+		cn := d.CoupleNumber()
+		if cn <= 0 || cn > couples {
+			r.Unpaired = dancers
+			return r
+		}
+		switch d.Gender() {
+		case Guy:
+			guys[cn] = d
+		case Gal:
+			gals[cn] = d
+		}
+	}
+	for cn := 1; cn <= couples; cn++ {
+		if guys[cn] == nil || gals[cn] == nil {
+			r.Unpaired = dancers
+			return r
+		}
+	}
+	for cn := 1; cn <= couples; cn++ {
+		partner := guys[cn].OriginalPartner()
+		if partner == nil {
+			partner = gals[cn]
+		}
+		if beside(guys[cn], partner) {
+			r.Paired += 1
+		} else {
+			r.Unpaired = append(r.Unpaired, guys[cn], partner)
+		}
+	}
+	angle := func(d Dancer) geometry.Direction {
+		return center.Direction(d.Position())
+	}
+	r.InSequence = inOrder(guys[1:], angle) && inOrder(gals[1:], angle)
+	offset := angle(gals[1]).Subtract(angle(guys[1]))
+	for cn := 2; cn <= couples; cn++ {
+		if !angle(gals[cn]).Subtract(angle(guys[cn])).Equal(offset) {
+			r.InSequence = false
+		}
+	}
+	promenading := func(d Dancer) bool {
+		return d.Direction().Equal(angle(d).QuarterLeft())
+	}
+	every := func(test func(cn int) bool) bool {
+		for cn := 1; cn <= couples; cn++ {
+			if !test(cn) {
+				return false
+			}
+		}
+		return true
+	}
+	home := NewSquaredSet(couples).Dancers()
+	switch {
+	case every(func(cn int) bool {
+		return atHome(guys[cn], home[2 * (cn - 1)], center) &&
+			atHome(gals[cn], home[2 * (cn - 1) + 1], center)
+	}):
+		r.Point = HomePoint
+	case !r.InSequence:
+		r.Point = NotResolved
+	case r.Paired == couples && every(func(cn int) bool {
+		return promenading(guys[cn])
+	}):
+		r.Point = PromenadePoint
+	case every(func(cn int) bool {
+		return facing(guys[cn], gals[cn]) && promenading(guys[cn])
+	}):
+		r.Point = RightAndLeftGrandPoint
+	case every(func(cn int) bool {
+		guy := guys[cn]
+		partner := gals[cn]
+		corner := gals[(cn + couples - 2) % couples + 1]
+		for _, gal := range gals[1:] {
+			if gal != partner && gal != corner &&
+				Distance(guy, gal) < Distance(guy, corner) {
+				return false
+			}
+		}
+		return toLeft(relativeDirection(guy, corner)) &&
+			toLeft(relativeDirection(guy, partner).Inverse())
+	}):
+		r.Point = AllemandeLeftPoint
+	}
+	if calls, ok := resolveCalls[r.Point]; ok {
+		r.Calls = calls
+	}
+	return r
+}
+
+// beside returns true if guy and gal are side by side, facing the
+// same way, with the guy on the left.
+func beside(guy, gal Dancer) bool {
+	return guy.Direction().Equal(gal.Direction()) &&
+		guy.Position().Equal(gal.Position().Add(
+			geometry.NewPosition(gal.Direction().QuarterLeft(),
+				geometry.CoupleDistance)))
+}
+
+// facing returns true if the two dancers are facing each other.
+func facing(d1, d2 Dancer) bool {
+	return d1.Position().Direction(d2.Position()).Equal(d1.Direction()) &&
+		d2.Position().Direction(d1.Position()).Equal(d2.Direction())
+}
+
+// relativeDirection returns the direction of d2 from d1's point of
+// view.  It is positive if d2 is to d1's left and negative if to d1's
+// right.
+func relativeDirection(d1, d2 Dancer) geometry.Direction {
+	return d1.Position().Direction(d2.Position()).Subtract(d1.Direction())
+}
+
+// toLeft returns true if the relative Direction is clearly to the
+// left rather than ahead or behind.
+func toLeft(d geometry.Direction) bool {
+	return d > 0 && !d.Equal(0) && !d.Equal(geometry.FullCircle / 2)
+}
+
+// atHome returns true if the Dancer is where home is, relative to
+// center.
+func atHome(d, home Dancer, center geometry.Position) bool {
+	return d.Direction().Equal(home.Direction()) &&
+		d.Position().Equal(home.Position().Add(center))
+}
+
+// inOrder returns true if the dancers are arranged counterclockwise
+// around the set, going around exactly once.
+func inOrder(dancers Dancers, angle func(Dancer) geometry.Direction) bool {
+	total := float32(0)
+	for i, d := range dancers {
+		next := dancers[(i + 1) % len(dancers)]
+		delta := angle(next).Subtract(angle(d))
+		if delta <= 0 {
+			delta += geometry.FullCircle
+		}
+		total += float32(delta)
+	}
+	return math.Abs(float64(total - 1)) < 0.01
+}
@@ -0,0 +1,74 @@
+package dancer
+
+import "testing"
+import "squaredance/geometry"
+
+
+func TestResolveHome(t *testing.T) {
+	set := NewSquaredSet(4)
+	r := set.Resolve()
+	if want, got := HomePoint, r.Point; got != want {
+		t.Errorf("Wrong ResolvePoint: want %v, got %v", want, got)
+	}
+	if want, got := 4, r.Paired; got != want {
+		t.Errorf("Wrong Paired: want %d, got %d", want, got)
+	}
+	if !r.InSequence {
+		t.Errorf("Not in sequence")
+	}
+	if len(r.Calls) != 0 {
+		t.Errorf("No calls should be needed: %v", r.Calls)
+	}
+}
+
+func TestResolveAllemandeLeft(t *testing.T) {
+	// Everyone moves one position to the right:
+	set := NewSquaredSet(4)
+	for _, d := range set.Dancers() {
+		d.Move(d.Position().Rotate(geometry.Direction1), d.Direction().QuarterLeft())
+	}
+	r := set.Resolve()
+	if want, got := AllemandeLeftPoint, r.Point; got != want {
+		t.Errorf("Wrong ResolvePoint: want %v, got %v", want, got)
+	}
+	if want, got := "Allemande Left", r.Calls[0]; got != want {
+		t.Errorf("Wrong first call: want %s, got %s", want, got)
+	}
+}
+
+func TestResolvePromenade(t *testing.T) {
+	set := NewSquaredSet(4)
+	promenade := func() {
+		for _, d := range set.Dancers() {
+			angle := geometry.Direction(0.5).Add(
+				geometry.Direction(0.25 * float32(d.CoupleNumber() - 1)))
+			radius := float32(1)
+			if d.Gender() == Gal {
+				radius = 2
+			}
+			d.Move(geometry.NewPosition(angle, radius), angle.QuarterLeft())
+		}
+	}
+	promenade()
+	r := set.Resolve()
+	if want, got := PromenadePoint, r.Point; got != want {
+		t.Errorf("Wrong ResolvePoint: want %v, got %v", want, got)
+	}
+	// Swap couples 2 and 3:
+	ds := set.Dancers()
+	for i := 2; i < 4; i++ {
+		p, d := ds[i].Position(), ds[i].Direction()
+		ds[i].Move(ds[i + 2].Position(), ds[i + 2].Direction())
+		ds[i + 2].Move(p, d)
+	}
+	r = set.Resolve()
+	if r.InSequence {
+		t.Errorf("Shouldn't be in sequence")
+	}
+	if r.Resolved() {
+		t.Errorf("Shouldn't be resolved: %s", r)
+	}
+	if want, got := 4, r.Paired; got != want {
+		t.Errorf("Wrong Paired: want %d, got %d", want, got)
+	}
+}
@@ -0,0 +1,196 @@
+// Package generator makes up random choreography.
+package generator
+
+import "fmt"
+import "math/rand"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/getout"
+import "squaredance/sequence"
+
+
+// Constraints describe what sort of Sequence Generate should produce.
+type Constraints struct {
+	// Level is the highest Level of FormationAction that may be
+	// used.
+	Level action.Level
+	// Beats is how long the Sequence should be.  Random Calls are
+	// added until it is at least this long.  A get out, if
+	// needed, comes after that.
+	Beats int
+	// MustInclude names Actions which must appear in the Sequence.
+	MustInclude []string
+	// AvoidRepeats prevents the same Call from being used twice
+	// anywhere in the Sequence, including the get out.
+	AvoidRepeats bool
+	// Resolve requires that the Sequence leave the dancers at a
+	// dancer.ResolvePoint.
+	Resolve bool
+	// GetOutDepth is how many Calls of get out may be added to
+	// resolve the square.  If it is 0 then DefaultGetOutDepth is
+	// used.
+	GetOutDepth int
+	// MaxAttempts is how many times to try to generate a Sequence
+	// before giving up.  If it is 0 then DefaultMaxAttempts is
+	// used.
+	MaxAttempts int
+}
+
+// DefaultGetOutDepth is the default value of Constraints.GetOutDepth.
+const DefaultGetOutDepth = 3
+
+// DefaultMaxAttempts is the default value of Constraints.MaxAttempts.
+const DefaultMaxAttempts = 20
+
+
+// ErrGenerationFailed is returned by Generate when it can't make a
+// Sequence that meets the Constraints.
+type ErrGenerationFailed struct {
+	Seed int64
+	Attempts int
+	// Reason describes why the last attempt failed.
+	Reason string
+}
+
+func (e *ErrGenerationFailed) Error() string {
+	return fmt.Sprintf("No sequence found with seed %d after %d attempts: %s",
+		e.Seed, e.Attempts, e.Reason)
+}
+
+
+// Generate returns a random Sequence that can be danced from a
+// squared set of four couples and that meets the Constraints.  The
+// same seed and Constraints always produce the same Sequence.
+func Generate(seed int64, c Constraints) (sequence.Sequence, error) {
+	if c.GetOutDepth <= 0 {
+		c.GetOutDepth = DefaultGetOutDepth
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = DefaultMaxAttempts
+	}
+	for _, name := range c.MustInclude {
+		if action.FindAction(name) == nil {
+			return nil, fmt.Errorf("Unknown action %q", name)
+		}
+	}
+	rng := rand.New(rand.NewSource(seed))
+	calls := getout.Calls(c.Level)
+	reason := ""
+	for attempt := 0; attempt < c.MaxAttempts; attempt++ {
+		var s sequence.Sequence
+		s, reason = generateOnce(rng, calls, c)
+		if reason == "" {
+			return s, nil
+		}
+	}
+	return nil, &ErrGenerationFailed{
+		Seed: seed,
+		Attempts: c.MaxAttempts,
+		Reason: reason,
+	}
+}
+
+// generateOnce tries to generate a Sequence.  If it fails it returns
+// the reason why.
+func generateOnce(rng *rand.Rand, calls []sequence.Call, c Constraints) (sequence.Sequence, string) {
+	set := dancer.NewSquaredSet(4)
+	center := set.FlagpoleCenter()
+	s := sequence.Sequence{}
+	used := map[string]bool{}
+	needed := map[string]bool{}
+	for _, name := range c.MustInclude {
+		needed[name] = true
+	}
+	for s.Beats() < c.Beats {
+		// Try the calls in a random order, but those which
+		// must be included first:
+		order := rng.Perm(len(calls))
+		tries := []sequence.Call{}
+		for _, i := range order {
+			if needed[calls[i].Action.Name()] {
+				tries = append(tries, calls[i])
+			}
+		}
+		for _, i := range order {
+			if !needed[calls[i].Action.Name()] {
+				tries = append(tries, calls[i])
+			}
+		}
+		added := false
+		for _, call := range tries {
+			if c.AvoidRepeats && used[call.String()] {
+				continue
+			}
+			before := set.Dancers().Fingerprint(center)
+			if err := (sequence.Sequence{ call }).Apply(set.Dancers()); err != nil {
+				continue
+			}
+			if set.Dancers().Fingerprint(center) == before {
+				// Nobody moved, so this call is pointless.
+				continue
+			}
+			s = append(s, call)
+			used[call.String()] = true
+			delete(needed, call.Action.Name())
+			added = true
+			break
+		}
+		if !added {
+			return nil, fmt.Sprintf("no call can follow %v", s)
+		}
+	}
+	if len(needed) > 0 {
+		return nil, fmt.Sprintf("%v doesn't include %v", s, needed)
+	}
+	if c.Resolve {
+		options := getout.Options{
+			Level: c.Level,
+			MaxDepth: c.GetOutDepth,
+		}
+		result, err := getout.Search(set, options)
+		if err != nil {
+			return nil, err.Error()
+		}
+		getOut := chooseGetOut(result.Solutions, used, c.AvoidRepeats)
+		if getOut == nil {
+			// Look further for one that doesn't repeat a Call:
+			options.MaxSolutions = getOutChoices
+			if result, err = getout.Search(set, options); err == nil {
+				getOut = chooseGetOut(result.Solutions, used, c.AvoidRepeats)
+			}
+		}
+		if getOut == nil {
+			return nil, fmt.Sprintf("every get out after %v repeats a call", s)
+		}
+		s = append(s, getOut...)
+	}
+	return s, ""
+}
+
+// getOutChoices is how many get outs are looked for when the first
+// one found repeats a Call.
+const getOutChoices = 10
+
+// chooseGetOut returns the first of the get outs which, if
+// avoidRepeats, has no Call that is in used or that is in it twice.
+// It returns nil if there isn't one.
+func chooseGetOut(getOuts []sequence.Sequence, used map[string]bool, avoidRepeats bool) sequence.Sequence {
+	for _, getOut := range getOuts {
+		if !avoidRepeats {
+			return getOut
+		}
+		seen := map[string]bool{}
+		ok := true
+		for _, call := range getOut {
+			if used[call.String()] || seen[call.String()] {
+				ok = false
+				break
+			}
+			seen[call.String()] = true
+		}
+		if ok {
+			return getOut
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package generator
+
+import "fmt"
+import "testing"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/sequence"
+
+
+func TestGenerate(t *testing.T) {
+	c := Constraints{
+		Level: action.Primitive,
+		Beats: 6,
+		MustInclude: []string{ "AboutFace" },
+		AvoidRepeats: true,
+		Resolve: true,
+	}
+	s, err := Generate(1, c)
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	t.Logf("Generated %v", s)
+	if s.Beats() < c.Beats {
+		t.Errorf("Too short: %d beats", s.Beats())
+	}
+	found := false
+	used := map[string]bool{}
+	for _, call := range s {
+		if call.Action.Name() == "AboutFace" {
+			found = true
+		}
+		if used[call.String()] {
+			t.Errorf("Repeated call %s in %v", call, s)
+		}
+		used[call.String()] = true
+	}
+	if !found {
+		t.Errorf("AboutFace is missing from %v", s)
+	}
+	run, err := s.Run(dancer.NewSquaredSet(4))
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if !run.Set.Resolve().Resolved() {
+		t.Errorf("Not resolved: %s", run.Set.Resolve())
+	}
+	for _, step := range run.Steps {
+		for _, p := range step.Result.Performances {
+			if p.FormationAction.Level() > c.Level {
+				t.Errorf("%s was done at %s", p.FormationAction, p.FormationAction.Level())
+			}
+		}
+	}
+	// The same seed gives the same sequence:
+	again, err := Generate(1, c)
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	if want, got := fmt.Sprint(s), fmt.Sprint(again); got != want {
+		t.Errorf("Not replayable: want %s, got %s", want, got)
+	}
+}
+
+func TestChooseGetOut(t *testing.T) {
+	quarterRight := sequence.NewCall(action.FindAction("QuarterRight"))
+	quarterLeft := sequence.NewCall(action.FindAction("QuarterLeft"))
+	aboutFace := sequence.NewCall(action.FindAction("AboutFace"))
+	getOuts := []sequence.Sequence{
+		// Repeats a Call from before the get out:
+		{ quarterRight },
+		// Repeats one of its own Calls:
+		{ quarterLeft, quarterLeft },
+		{ quarterLeft, aboutFace },
+	}
+	used := map[string]bool{ quarterRight.String(): true }
+	if want, got := fmt.Sprint(getOuts[0]), fmt.Sprint(chooseGetOut(getOuts, used, false)); got != want {
+		t.Errorf("Wrong get out without AvoidRepeats: want %s, got %s", want, got)
+	}
+	if want, got := fmt.Sprint(getOuts[2]), fmt.Sprint(chooseGetOut(getOuts, used, true)); got != want {
+		t.Errorf("Wrong get out with AvoidRepeats: want %s, got %s", want, got)
+	}
+	if got := chooseGetOut(getOuts[:2], used, true); got != nil {
+		t.Errorf("Expected no get out, got %v", got)
+	}
+}
@@ -236,6 +236,18 @@ func (p Position) Angle() Direction {
 	return Direction(math.Atan2(l, d) / (2 * math.Pi))
 }
 
+// Rotate returns the Position, interpreted as a vector, rotated by
+// the specified relative Direction.
+func (p Position) Rotate(d Direction) Position {
+	return NewPosition(p.Angle().Add(d), p.Magnitude())
+}
+
+// Dot returns the dot product of the two Positions interpreted as
+// vectors.
+func (p1 Position) Dot(p2 Position) float32 {
+	return float32(p1.Down) * float32(p2.Down) + float32(p1.Left) * float32(p2.Left)
+}
+
 // Center returns a new Position that's at the center of the specified
 // Positions.
 func Center(positions ...Position) Position {
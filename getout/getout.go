@@ -0,0 +1,201 @@
+// Package getout searches for calls which resolve the square.
+//
+// The search is an iterative deepening depth first search over the
+// Actions at or below a given Level, each of which might be done by
+// everyone or by one of the ubiquitous Roles like OriginalHeads.
+// Arrangements of the dancers that have already been seen are pruned.
+// A rotation of the whole set isn't the same arrangement, since being
+// at home and who the CurrentHeads are depend on where the dancers are
+// relative to the caller.  The dancers' partner pairing and sequence
+// order are used to try the most promising calls first.
+package getout
+
+import "fmt"
+import "sort"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/reasoning"
+import "squaredance/sequence"
+
+
+// Options control the search.
+type Options struct {
+	// Level is the highest Level of FormationAction that may be
+	// used.
+	Level action.Level
+	// MaxDepth is the longest get out that will be looked for.
+	MaxDepth int
+	// MaxNodes bounds how many arrangements of the dancers will
+	// be examined.  If it is 0 then DefaultMaxNodes is used.
+	MaxNodes int
+	// MaxSolutions is how many get outs of the shortest length
+	// to return.  If it is 0 then only one is returned.
+	MaxSolutions int
+}
+
+// DefaultMaxNodes is the default value for Options.MaxNodes.
+const DefaultMaxNodes = 10000
+
+// Designators are the Roles that Calls in a get out can be
+// designated with, besides everyone.
+var Designators = []string{
+	"OriginalHeads",
+	"OriginalSides",
+	"CurrentHeads",
+	"CurrentSides",
+}
+
+
+// Result is the outcome of a successful Search.
+type Result struct {
+	// Solutions are the shortest get outs that were found.
+	Solutions []sequence.Sequence
+	// Nodes is how many arrangements of the dancers were
+	// examined.
+	Nodes int
+}
+
+
+// ErrNoGetOut is returned by Search if no get out was found.
+type ErrNoGetOut struct {
+	MaxDepth int
+	Nodes int
+}
+
+func (e *ErrNoGetOut) Error() string {
+	return fmt.Sprintf("No get out of up to %d calls found after examining %d positions",
+		e.MaxDepth, e.Nodes)
+}
+
+
+type searcher struct {
+	options Options
+	center geometry.Position
+	calls []sequence.Call
+	nodes int
+	// seen maps Fingerprints to the shallowest depth they've
+	// been reached at during the current iteration.
+	seen map[string]int
+	path sequence.Sequence
+	solutions []sequence.Sequence
+}
+
+// Search looks for the shortest sequences of Calls that would leave
+// the dancers of the Set at a dancer.ResolvePoint.  The Set itself is
+// not changed.
+func Search(set dancer.Set, options Options) (*Result, error) {
+	if options.MaxNodes <= 0 {
+		options.MaxNodes = DefaultMaxNodes
+	}
+	if options.MaxSolutions <= 0 {
+		options.MaxSolutions = 1
+	}
+	s := &searcher{
+		options: options,
+		center: set.FlagpoleCenter(),
+		calls: Calls(options.Level),
+	}
+	for depth := 0; depth <= options.MaxDepth; depth++ {
+		s.seen = map[string]int{}
+		s.path = sequence.Sequence{}
+		s.solutions = []sequence.Sequence{}
+		s.search(set.Dancers().Copy(), depth)
+		if len(s.solutions) > 0 {
+			return &Result{
+				Solutions: s.solutions,
+				Nodes: s.nodes,
+			}, nil
+		}
+		if s.nodes >= options.MaxNodes {
+			break
+		}
+	}
+	return nil, &ErrNoGetOut{
+		MaxDepth: options.MaxDepth,
+		Nodes: s.nodes,
+	}
+}
+
+// Calls returns every Call that Search might try: each Action with a
+// FormationAction at or below level, done by everyone or by one of the
+// Designators.  Each Action is restricted with action.AtLevel so that
+// it is only done from Formations that dancers at level would know.
+func Calls(level action.Level) []sequence.Call {
+	roles := []reasoning.Role{}
+	for _, name := range Designators {
+		if role := reasoning.LookupRole(name); role != nil {
+			roles = append(roles, role)
+		}
+	}
+	calls := []sequence.Call{}
+	for _, a := range action.ActionsAtOrBelow(level) {
+		a = action.AtLevel(a, level)
+		calls = append(calls, sequence.NewCall(a))
+		for _, role := range roles {
+			calls = append(calls, sequence.NewCall(a, role))
+		}
+	}
+	return calls
+}
+
+// score estimates how close the dancers are to being resolved.
+// Higher is better.
+func score(r *dancer.Resolution) int {
+	s := 2 * r.Paired
+	if r.InSequence {
+		s += 1
+	}
+	return s
+}
+
+type candidate struct {
+	call sequence.Call
+	dancers dancer.Dancers
+	score int
+}
+
+// search does a depth limited search from dancers, which are copies
+// that the search is free to move.
+func (s *searcher) search(dancers dancer.Dancers, depth int) {
+	s.nodes += 1
+	if dancer.ResolveDancers(dancers, s.center).Resolved() {
+		solution := append(sequence.Sequence{}, s.path...)
+		s.solutions = append(s.solutions, solution)
+		return
+	}
+	if depth == 0 || s.nodes >= s.options.MaxNodes {
+		return
+	}
+	fingerprint := dancers.Fingerprint(s.center)
+	if d, ok := s.seen[fingerprint]; ok && d >= depth {
+		return
+	}
+	s.seen[fingerprint] = depth
+	candidates := []candidate{}
+	for _, call := range s.calls {
+		next := dancers.Copy()
+		result, err := action.PerformChecked(next, call.Action,
+			action.BreatheOnCollision, call.Designators...)
+		if err != nil || !result.Complete() {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			call: call,
+			dancers: next,
+			score: score(dancer.ResolveDancers(next, s.center)),
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	for _, c := range candidates {
+		if len(s.solutions) >= s.options.MaxSolutions ||
+			s.nodes >= s.options.MaxNodes {
+			return
+		}
+		s.path = append(s.path, c.call)
+		s.search(c.dancers, depth - 1)
+		s.path = s.path[:len(s.path) - 1]
+	}
+}
@@ -0,0 +1,90 @@
+package getout
+
+import "errors"
+import "testing"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/reasoning"
+
+
+func TestAlreadyResolved(t *testing.T) {
+	result, err := Search(dancer.NewSquaredSet(4), Options{ Level: action.Primitive, MaxDepth: 2 })
+	if err != nil {
+		t.Fatalf("Search: %s", err)
+	}
+	if want, got := 0, len(result.Solutions[0]); got != want {
+		t.Errorf("Wrong solution length: want %d, got %d", want, got)
+	}
+}
+
+func TestOneCallGetOut(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	if _, err := action.Perform(set, action.FindAction("QuarterRight"),
+		reasoning.LookupRole("OriginalHeads")); err != nil {
+		t.Fatalf("Perform: %s", err)
+	}
+	if set.Resolve().Resolved() {
+		t.Fatalf("Set shouldn't be resolved: %s", set.Resolve())
+	}
+	result, err := Search(set, Options{ Level: action.Primitive, MaxDepth: 2 })
+	if err != nil {
+		t.Fatalf("Search: %s", err)
+	}
+	solution := result.Solutions[0]
+	if want, got := 1, len(solution); got != want {
+		t.Fatalf("Wrong solution length: want %d, got %d: %v", want, got, solution)
+	}
+	t.Logf("Get out: %v, %d nodes", solution, result.Nodes)
+	// The search didn't move the real dancers:
+	if set.Resolve().Resolved() {
+		t.Errorf("Search moved the dancers")
+	}
+	// Doing the get out resolves the square:
+	run, err := solution.Run(set)
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if !run.Set.Resolve().Resolved() {
+		t.Errorf("Get out %v didn't resolve the square", solution)
+	}
+}
+
+func TestRotatedGetOut(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	if _, err := action.Perform(set, action.FindAction("QuarterLeft")); err != nil {
+		t.Fatalf("Perform: %s", err)
+	}
+	if set.Resolve().Resolved() {
+		t.Fatalf("Set shouldn't be resolved: %s", set.Resolve())
+	}
+	result, err := Search(set, Options{ Level: action.Primitive, MaxDepth: 2 })
+	if err != nil {
+		t.Fatalf("Search: %s", err)
+	}
+	solution := result.Solutions[0]
+	if want, got := 1, len(solution); got != want {
+		t.Fatalf("Wrong solution length: want %d, got %d: %v", want, got, solution)
+	}
+	t.Logf("Get out: %v, %d nodes", solution, result.Nodes)
+	run, err := solution.Run(set)
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if !run.Set.Resolve().Resolved() {
+		t.Errorf("Get out %v didn't resolve the square", solution)
+	}
+}
+
+func TestNoGetOut(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	// Swap the positions of two guys:
+	ds := set.Dancers()
+	p, d := ds[0].Position(), ds[0].Direction()
+	ds[0].Move(ds[4].Position(), ds[4].Direction())
+	ds[4].Move(p, d)
+	_, err := Search(set, Options{ Level: action.Primitive, MaxDepth: 1 })
+	var none *ErrNoGetOut
+	if !errors.As(err, &none) {
+		t.Errorf("Expected ErrNoGetOut, got %v", err)
+	}
+}
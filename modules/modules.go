@@ -0,0 +1,338 @@
+// Package modules keeps a library of choreographic modules.
+//
+// Callers build sequences out of modules: short pieces of
+// choreography with known effects.  A zero leaves every dancer where
+// it started.  An equivalent has the same effect as some other
+// sequence of calls.  A get in takes the dancers from a squared set to
+// some useful arrangement.  Modules are keyed by the
+// dancer.CanonicalFingerprint of the arrangement of the dancers that
+// they start from.
+package modules
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "sort"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/reasoning"
+import "squaredance/sequence"
+
+
+// Kind identifies what sort of Module a Module is.
+type Kind int
+
+const (
+	Zero Kind = iota
+	Equivalent
+	GetIn
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Zero:
+		return "Zero"
+	case Equivalent:
+		return "Equivalent"
+	case GetIn:
+		return "GetIn"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// ParseKind returns the Kind with the specified name.
+func ParseKind(name string) (Kind, error) {
+	for _, k := range []Kind{ Zero, Equivalent, GetIn } {
+		if k.String() == name {
+			return k, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown module kind %q", name)
+}
+
+
+// Module is a piece of choreography with a known effect.
+type Module struct {
+	Kind Kind
+	Name string
+	// Start is the CanonicalFingerprint of the arrangement of the
+	// dancers that the Module starts from.
+	Start string
+	// End is the CanonicalFingerprint of the arrangement of the
+	// dancers that the Module leaves them in.
+	End string
+	Calls sequence.Sequence
+	// EquivalentTo is, for an Equivalent, the sequence that Calls
+	// is equivalent to.
+	EquivalentTo sequence.Sequence
+}
+
+func (m *Module) String() string {
+	if m.Kind == Equivalent {
+		return fmt.Sprintf("%s %s: %v = %v", m.Kind, m.Name, m.Calls, m.EquivalentTo)
+	}
+	return fmt.Sprintf("%s %s: %v", m.Kind, m.Name, m.Calls)
+}
+
+
+// Library is a collection of Modules.
+type Library struct {
+	byStart map[string][]*Module
+}
+
+// NewLibrary returns a new, empty Library.
+func NewLibrary() *Library {
+	return &Library{
+		byStart: map[string][]*Module{},
+	}
+}
+
+// Add adds the Module to the Library.
+func (lib *Library) Add(m *Module) {
+	lib.byStart[m.Start] = append(lib.byStart[m.Start], m)
+}
+
+// Lookup returns the Modules that start from the arrangement with the
+// specified CanonicalFingerprint.
+func (lib *Library) Lookup(start string) []*Module {
+	return lib.byStart[start]
+}
+
+// LookupDancers returns the Modules that start from the current
+// arrangement of the dancers around center.
+func (lib *Library) LookupDancers(dancers dancer.Dancers, center geometry.Position) []*Module {
+	return lib.Lookup(dancers.CanonicalFingerprint(center))
+}
+
+// Modules returns all of the Modules in the Library.
+func (lib *Library) Modules() []*Module {
+	starts := []string{}
+	for start := range lib.byStart {
+		starts = append(starts, start)
+	}
+	sort.Strings(starts)
+	all := []*Module{}
+	for _, start := range starts {
+		all = append(all, lib.byStart[start]...)
+	}
+	return all
+}
+
+
+// Outcome returns the CanonicalFingerprint of the arrangement that
+// the dancers would be in after dancing the Sequence.  The dancers
+// themselves don't move.
+func Outcome(dancers dancer.Dancers, center geometry.Position, s sequence.Sequence) (string, error) {
+	copies := dancers.Copy()
+	if err := s.Apply(copies); err != nil {
+		return "", err
+	}
+	return copies.CanonicalFingerprint(center), nil
+}
+
+// SameOutcome returns true if dancing either Sequence from the
+// current arrangement of the dancers would leave them in the same
+// arrangement, up to rotation of the whole set.
+func SameOutcome(dancers dancer.Dancers, center geometry.Position, s1, s2 sequence.Sequence) (bool, error) {
+	o1, err := Outcome(dancers, center, s1)
+	if err != nil {
+		return false, err
+	}
+	o2, err := Outcome(dancers, center, s2)
+	if err != nil {
+		return false, err
+	}
+	return o1 == o2, nil
+}
+
+// IsZero returns true if the Sequence leaves every dancer where it
+// started, facing the same way.  Unlike SameOutcome, a rotation of the
+// whole set doesn't count.
+func IsZero(dancers dancer.Dancers, center geometry.Position, s sequence.Sequence) (bool, error) {
+	copies := dancers.Copy()
+	if err := s.Apply(copies); err != nil {
+		return false, err
+	}
+	return copies.Fingerprint(center) == dancers.Fingerprint(center), nil
+}
+
+// AddZero adds the Sequence to the Library as a Zero if it is one.
+func (lib *Library) AddZero(name string, dancers dancer.Dancers, center geometry.Position, s sequence.Sequence) (*Module, error) {
+	zero, err := IsZero(dancers, center, s)
+	if err != nil {
+		return nil, err
+	}
+	if !zero {
+		return nil, fmt.Errorf("%v is not a zero", s)
+	}
+	start := dancers.CanonicalFingerprint(center)
+	m := &Module{
+		Kind: Zero,
+		Name: name,
+		Start: start,
+		End: start,
+		Calls: s,
+	}
+	lib.Add(m)
+	return m, nil
+}
+
+// AddEquivalent adds s1 to the Library as an Equivalent of s2 if they
+// have the same outcome.
+func (lib *Library) AddEquivalent(name string, dancers dancer.Dancers, center geometry.Position, s1, s2 sequence.Sequence) (*Module, error) {
+	o1, err := Outcome(dancers, center, s1)
+	if err != nil {
+		return nil, err
+	}
+	o2, err := Outcome(dancers, center, s2)
+	if err != nil {
+		return nil, err
+	}
+	if o1 != o2 {
+		return nil, fmt.Errorf("%v is not equivalent to %v", s1, s2)
+	}
+	m := &Module{
+		Kind: Equivalent,
+		Name: name,
+		Start: dancers.CanonicalFingerprint(center),
+		End: o1,
+		Calls: s1,
+		EquivalentTo: s2,
+	}
+	lib.Add(m)
+	return m, nil
+}
+
+// AddGetIn adds the Sequence to the Library as a GetIn from the
+// current arrangement of the dancers.
+func (lib *Library) AddGetIn(name string, dancers dancer.Dancers, center geometry.Position, s sequence.Sequence) (*Module, error) {
+	end, err := Outcome(dancers, center, s)
+	if err != nil {
+		return nil, err
+	}
+	m := &Module{
+		Kind: GetIn,
+		Name: name,
+		Start: dancers.CanonicalFingerprint(center),
+		End: end,
+		Calls: s,
+	}
+	lib.Add(m)
+	return m, nil
+}
+
+
+// LibraryVersion is the version of the format written by Save.
+const LibraryVersion = 1
+
+type jsonLibrary struct {
+	Version int              `json:"version"`
+	Modules []jsonModule     `json:"modules"`
+}
+
+type jsonModule struct {
+	Kind string               `json:"kind"`
+	Name string               `json:"name,omitempty"`
+	Start string              `json:"start"`
+	End string                `json:"end"`
+	Calls []jsonCall          `json:"calls"`
+	EquivalentTo []jsonCall   `json:"equivalentTo,omitempty"`
+}
+
+type jsonCall struct {
+	Action string             `json:"action"`
+	Designators []string      `json:"designators,omitempty"`
+}
+
+func toJSONCalls(s sequence.Sequence) []jsonCall {
+	calls := []jsonCall{}
+	for _, c := range s {
+		jc := jsonCall{ Action: c.Action.Name() }
+		for _, role := range c.Designators {
+			jc.Designators = append(jc.Designators, role.Name())
+		}
+		calls = append(calls, jc)
+	}
+	return calls
+}
+
+func fromJSONCalls(calls []jsonCall) (sequence.Sequence, error) {
+	s := sequence.Sequence{}
+	for _, jc := range calls {
+		a := action.FindAction(jc.Action)
+		if a == nil {
+			return nil, fmt.Errorf("Unknown action %q", jc.Action)
+		}
+		roles := []reasoning.Role{}
+		for _, name := range jc.Designators {
+			role := reasoning.LookupRole(name)
+			if role == nil {
+				return nil, fmt.Errorf("Unknown role %q", name)
+			}
+			roles = append(roles, role)
+		}
+		s = append(s, sequence.NewCall(a, roles...))
+	}
+	return s, nil
+}
+
+// Save writes the Library as JSON.
+func (lib *Library) Save(w io.Writer) error {
+	jl := jsonLibrary{
+		Version: LibraryVersion,
+		Modules: []jsonModule{},
+	}
+	for _, m := range lib.Modules() {
+		jm := jsonModule{
+			Kind: m.Kind.String(),
+			Name: m.Name,
+			Start: m.Start,
+			End: m.End,
+			Calls: toJSONCalls(m.Calls),
+		}
+		if m.Kind == Equivalent {
+			jm.EquivalentTo = toJSONCalls(m.EquivalentTo)
+		}
+		jl.Modules = append(jl.Modules, jm)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jl)
+}
+
+// Load reads a Library that was written by Save.
+func Load(r io.Reader) (*Library, error) {
+	jl := jsonLibrary{}
+	if err := json.NewDecoder(r).Decode(&jl); err != nil {
+		return nil, err
+	}
+	if jl.Version != LibraryVersion {
+		return nil, fmt.Errorf("Unsupported module library version %d", jl.Version)
+	}
+	lib := NewLibrary()
+	for _, jm := range jl.Modules {
+		kind, err := ParseKind(jm.Kind)
+		if err != nil {
+			return nil, err
+		}
+		calls, err := fromJSONCalls(jm.Calls)
+		if err != nil {
+			return nil, err
+		}
+		m := &Module{
+			Kind: kind,
+			Name: jm.Name,
+			Start: jm.Start,
+			End: jm.End,
+			Calls: calls,
+		}
+		if kind == Equivalent {
+			if m.EquivalentTo, err = fromJSONCalls(jm.EquivalentTo); err != nil {
+				return nil, err
+			}
+		}
+		lib.Add(m)
+	}
+	return lib, nil
+}
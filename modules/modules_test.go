@@ -0,0 +1,79 @@
+package modules
+
+import "bytes"
+import "testing"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/notation"
+import "squaredance/sequence"
+
+
+func calls(names ...string) sequence.Sequence {
+	s := sequence.Sequence{}
+	for _, name := range names {
+		s = append(s, sequence.NewCall(action.FindAction(name)))
+	}
+	return s
+}
+
+func TestZerosAndEquivalents(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	dancers := set.Dancers()
+	center := set.FlagpoleCenter()
+	lib := NewLibrary()
+	if _, err := lib.AddZero("Four Quarters", dancers, center,
+		calls("QuarterRight", "QuarterRight", "QuarterRight", "QuarterRight")); err != nil {
+		t.Errorf("AddZero: %s", err)
+	}
+	if _, err := lib.AddZero("Not a zero", dancers, center, calls("QuarterRight")); err == nil {
+		t.Errorf("QuarterRight shouldn't be a zero")
+	}
+	if _, err := lib.AddEquivalent("Two Quarters", dancers, center,
+		calls("QuarterRight", "QuarterRight"), calls("AboutFace")); err != nil {
+		t.Errorf("AddEquivalent: %s", err)
+	}
+	if want, got := 2, len(lib.LookupDancers(dancers, center)); got != want {
+		t.Errorf("Wrong number of modules: want %d, got %d", want, got)
+	}
+	// Trying things out didn't move the dancers:
+	if !set.Resolve().Resolved() {
+		t.Errorf("The dancers moved")
+	}
+	buf := bytes.NewBufferString("")
+	if err := lib.Save(buf); err != nil {
+		t.Fatalf("Save: %s", err)
+	}
+	loaded, err := Load(buf)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	all := loaded.Modules()
+	if want, got := 2, len(all); got != want {
+		t.Fatalf("Wrong number of loaded modules: want %d, got %d", want, got)
+	}
+	if want, got := lib.Modules()[1].String(), all[1].String(); got != want {
+		t.Errorf("Module changed: want %s, got %s", want, got)
+	}
+}
+
+func TestRotationIsNotZero(t *testing.T) {
+	// A lone dancer at the center who turns a quarter has rotated
+	// the whole set:
+	dancers := notation.MustParse("1v\n")
+	center := dancers[0].Position()
+	turned := dancers.Copy()
+	if err := calls("QuarterRight").Apply(turned); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if turned.CanonicalFingerprint(center) != dancers.CanonicalFingerprint(center) {
+		t.Fatalf("A quarter turn should have the same CanonicalFingerprint")
+	}
+	lib := NewLibrary()
+	if _, err := lib.AddZero("Quarter", dancers, center, calls("QuarterRight")); err == nil {
+		t.Errorf("Turning the whole set a quarter shouldn't be a zero")
+	}
+	if _, err := lib.AddZero("Four Quarters", dancers, center,
+		calls("QuarterRight", "QuarterRight", "QuarterRight", "QuarterRight")); err != nil {
+		t.Errorf("AddZero: %s", err)
+	}
+}
@@ -0,0 +1,456 @@
+// Package notation reads and writes formations as text diagrams.
+//
+// A diagram is a grid.  Each line is a row, with rows going down the
+// page in the Down direction, and each whitespace separated token is a
+// cell, with cells going across the page in the Left direction.  This
+// matches the orientation used by package svg.  A cell is either "."
+// for no dancer or an optional couple number followed by a mark that
+// gives the dancer's Gender and which way it faces:
+//
+//	guy  gal  unspecified  facing
+//	 ^    u       U        up the page
+//	 v    d       D        down the page
+//	 <    l       L        left across the page
+//	 >    r       R        right across the page
+//
+// For example, a squared set of four couples is
+//
+//	.  1d 1v .
+//	2> .  .  4l
+//	2r .  .  4<
+//	.  3^ 3u .
+//
+// Adjacent cells are DefaultSpacing apart unless the diagram starts
+// with a line like "# spacing 0.5".  Other lines starting with "#" are
+// comments.
+package notation
+
+import "bufio"
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "math"
+import "sort"
+import "strconv"
+import "strings"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+// DefaultSpacing is the distance between adjacent cells of a diagram
+// with no spacing line.
+const DefaultSpacing = geometry.CoupleDistance
+
+const spacingPrefix = "# spacing "
+
+// marks maps each Gender to the marks for facing down, right, up and
+// left the page, which are Directions 0, 0.25, 0.5 and 0.75.
+var marks = map[dancer.Gender]string{
+	dancer.Guy: "v>^<",
+	dancer.Gal: "drul",
+	dancer.Unspecified: "DRUL",
+}
+
+
+// ErrSyntax is returned by Parse when a diagram can't be understood.
+type ErrSyntax struct {
+	// Line and Column, which start at 1, locate the problem.
+	Line, Column int
+	Token string
+	Reason string
+}
+
+func (e *ErrSyntax) Error() string {
+	return fmt.Sprintf("Line %d, column %d, %q: %s", e.Line, e.Column, e.Token, e.Reason)
+}
+
+
+type cell struct {
+	row, column int
+	coupleNumber int
+	gender dancer.Gender
+	direction geometry.Direction
+	// line and col locate the token for error messages.
+	line, col int
+	token string
+}
+
+func parseCell(token string) (*cell, string) {
+	mark := token[len(token) - 1:]
+	c := &cell{ coupleNumber: -1, token: token }
+	found := false
+	for gender, m := range marks {
+		if i := strings.Index(m, mark); i >= 0 {
+			c.gender = gender
+			c.direction = geometry.Direction(float32(i) / 4)
+			found = true
+		}
+	}
+	if !found {
+		return nil, "unknown mark " + mark
+	}
+	if number := token[:len(token) - 1]; number != "" {
+		n, err := strconv.Atoi(number)
+		if err != nil || n <= 0 {
+			return nil, "bad couple number " + number
+		}
+		c.coupleNumber = n
+	}
+	return c, ""
+}
+
+// Parse reads a diagram and returns the dancers in it, centered
+// around geometry.Origin.  Dancers with couple numbers are given the
+// Ordinals they would have in dancer.NewSquaredSet and those of the
+// same couple are each other's OriginalPartner.  Any others are
+// numbered after them in reading order.
+func Parse(diagram string) (dancer.Dancers, error) {
+	spacing := float32(DefaultSpacing)
+	cells := []*cell{}
+	scanner := bufio.NewScanner(strings.NewReader(diagram))
+	line, row := 0, 0
+	rowStarted := false
+	for scanner.Scan() {
+		line += 1
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, spacingPrefix) {
+			s, err := strconv.ParseFloat(strings.TrimSpace(trimmed[len(spacingPrefix):]), 32)
+			if err != nil || s <= 0 {
+				return nil, &ErrSyntax{
+					Line: line, Column: 1, Token: trimmed,
+					Reason: "bad spacing",
+				}
+			}
+			spacing = float32(s)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if trimmed == "" {
+			// Blank lines before the first row are ignored.
+			// Others count as empty rows.
+			if rowStarted {
+				row += 1
+			}
+			continue
+		}
+		rowStarted = true
+		column := 0
+		for _, field := range fieldsWithColumns(text) {
+			if field.text != "." {
+				c, reason := parseCell(field.text)
+				if c == nil {
+					return nil, &ErrSyntax{
+						Line: line, Column: field.column, Token: field.text,
+						Reason: reason,
+					}
+				}
+				c.row, c.column = row, column
+				c.line, c.col = line, field.column
+				cells = append(cells, c)
+			}
+			column += 1
+		}
+		row += 1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return makeDancers(cells, spacing)
+}
+
+type field struct {
+	text string
+	// column starts at 1.
+	column int
+}
+
+func fieldsWithColumns(line string) []field {
+	fields := []field{}
+	start := -1
+	for i, r := range line + " " {
+		if r == ' ' || r == '\t' {
+			if start >= 0 {
+				fields = append(fields, field{ line[start:i], start + 1 })
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	return fields
+}
+
+func makeDancers(cells []*cell, spacing float32) (dancer.Dancers, error) {
+	dancers := dancer.Dancers{}
+	if len(cells) == 0 {
+		return dancers, nil
+	}
+	minRow, maxRow := cells[0].row, cells[0].row
+	minColumn, maxColumn := cells[0].column, cells[0].column
+	for _, c := range cells {
+		if c.row < minRow {
+			minRow = c.row
+		}
+		if c.row > maxRow {
+			maxRow = c.row
+		}
+		if c.column < minColumn {
+			minColumn = c.column
+		}
+		if c.column > maxColumn {
+			maxColumn = c.column
+		}
+	}
+	centerRow := float32(minRow + maxRow) / 2
+	centerColumn := float32(minColumn + maxColumn) / 2
+	taken := map[int]*cell{}
+	numbered := 0
+	for _, c := range cells {
+		if c.coupleNumber > numbered {
+			numbered = c.coupleNumber
+		}
+	}
+	next := 2 * numbered
+	byCouple := map[int]map[dancer.Gender]dancer.Dancer{}
+	for _, c := range cells {
+		ordinal := next
+		if c.coupleNumber > 0 && c.gender != dancer.Unspecified {
+			ordinal = 2 * (c.coupleNumber - 1)
+			if c.gender == dancer.Gal {
+				ordinal += 1
+			}
+			if other, ok := taken[ordinal]; ok {
+				return nil, &ErrSyntax{
+					Line: c.line, Column: c.col, Token: c.token,
+					Reason: fmt.Sprintf("same dancer as line %d, column %d",
+						other.line, other.col),
+				}
+			}
+		} else {
+			next += 1
+		}
+		taken[ordinal] = c
+		d := dancer.NewDancer(ordinal, c.coupleNumber, c.gender)
+		d.Move(geometry.NewPositionDownLeft(
+			geometry.Down((float32(c.row) - centerRow) * spacing),
+			geometry.Left((float32(c.column) - centerColumn) * spacing)),
+			c.direction)
+		dancers = append(dancers, d)
+		if c.coupleNumber > 0 {
+			if byCouple[c.coupleNumber] == nil {
+				byCouple[c.coupleNumber] = map[dancer.Gender]dancer.Dancer{}
+			}
+			byCouple[c.coupleNumber][c.gender] = d
+		}
+	}
+	for _, couple := range byCouple {
+		guy, gal := couple[dancer.Guy], couple[dancer.Gal]
+		if guy != nil && gal != nil {
+			guy.SetOriginalPartner(gal)
+			gal.SetOriginalPartner(guy)
+		}
+	}
+	sort.Sort(dancers)
+	return dancers, nil
+}
+
+// ParseDancers returns the dancers described by data, which is either
+// the JSON of a dancer.Set, the JSON of dancer.Dancers or a diagram.
+func ParseDancers(data []byte) (dancer.Dancers, error) {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case isSetJSON(trimmed):
+		set, err := dancer.UnmarshalSet(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return set.Dancers(), nil
+	case bytes.HasPrefix(trimmed, []byte("{")), bytes.HasPrefix(trimmed, []byte("[")):
+		dancers := dancer.Dancers{}
+		if err := dancers.UnmarshalJSON(trimmed); err != nil {
+			return nil, err
+		}
+		return dancers, nil
+	}
+	return Parse(string(data))
+}
+
+// isSetJSON returns true if data is a JSON object with the fields of a
+// dancer.SetJSON rather than just those of a dancer.DancersJSON.
+func isSetJSON(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("{")) {
+		return false
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	_, ok := fields["centerDown"]
+	return ok
+}
+
+// MustParse is like Parse but panics if the diagram can't be parsed.
+// It is intended for diagrams which are fixed in the source code, as
+// in tests.
+func MustParse(diagram string) dancer.Dancers {
+	dancers, err := Parse(diagram)
+	if err != nil {
+		panic(err)
+	}
+	return dancers
+}
+
+
+// gridSpacings are the spacings that Format tries, largest first.
+var gridSpacings = []float32{ 1, 0.5, 0.25, 0.125 }
+
+// gridTolerance is how far, as a fraction of the spacing, a dancer
+// can be from a grid point for Format to consider it on the grid.
+const gridTolerance = 0.1
+
+// sharedMark is written by Format in a cell that more than one dancer
+// is nearest to.  It isn't a mark that Parse accepts.
+const sharedMark = "*"
+
+// ErrInexact is returned by Format when no spacing puts every dancer
+// on the grid in a cell of its own.  The diagram is still returned,
+// in the finest spacing, but it doesn't show exactly where the dancers
+// are.
+type ErrInexact struct {
+	Spacing float32
+	// Shared are the dancers which are nearest to the same cell as
+	// some other dancer.  Those cells are marked with a "*".
+	Shared dancer.Dancers
+	// OffGrid are the dancers which aren't close to any cell and
+	// are shown in the nearest one.
+	OffGrid dancer.Dancers
+}
+
+func (e *ErrInexact) Error() string {
+	problems := []string{}
+	if len(e.Shared) > 0 {
+		problems = append(problems, "sharing cells: " + dancerNames(e.Shared))
+	}
+	if len(e.OffGrid) > 0 {
+		problems = append(problems, "off the grid: " + dancerNames(e.OffGrid))
+	}
+	return fmt.Sprintf("Diagram with spacing %s is inexact, %s",
+		strconv.FormatFloat(float64(e.Spacing), 'f', -1, 32),
+		strings.Join(problems, "; "))
+}
+
+func dancerNames(dancers dancer.Dancers) string {
+	names := []string{}
+	for _, d := range dancers {
+		names = append(names, d.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// Format returns a diagram of the dancers.  It uses the largest
+// spacing in which every dancer is on the grid and no two dancers
+// share a cell.  Directions are rounded to the nearest quarter turn.
+// If there is no such spacing then the diagram is drawn with the
+// finest spacing and an ErrInexact is returned with it.
+func Format(dancers dancer.Dancers) (string, error) {
+	if len(dancers) == 0 {
+		return "", nil
+	}
+	// The top left corner of the grid:
+	top, right := dancers[0].Position().Down, dancers[0].Position().Left
+	for _, d := range dancers {
+		if d.Position().Down < top {
+			top = d.Position().Down
+		}
+		if d.Position().Left < right {
+			right = d.Position().Left
+		}
+	}
+	var spacing float32
+	var grid map[[2]int]dancer.Dancers
+	var offGrid dancer.Dancers
+	var rows, columns int
+	for _, s := range gridSpacings {
+		spacing = s * geometry.CoupleDistance
+		grid = map[[2]int]dancer.Dancers{}
+		offGrid = dancer.Dancers{}
+		rows, columns = 0, 0
+		ok := true
+		for _, d := range dancers {
+			r, rOK := onGrid(float32(d.Position().Down - top), spacing)
+			c, cOK := onGrid(float32(d.Position().Left - right), spacing)
+			if !rOK || !cOK {
+				offGrid = append(offGrid, d)
+				ok = false
+			}
+			if len(grid[[2]int{ r, c }]) > 0 {
+				ok = false
+			}
+			grid[[2]int{ r, c }] = append(grid[[2]int{ r, c }], d)
+			if r >= rows {
+				rows = r + 1
+			}
+			if c >= columns {
+				columns = c + 1
+			}
+		}
+		if ok {
+			break
+		}
+	}
+	buf := bytes.NewBufferString("")
+	if spacing != DefaultSpacing {
+		fmt.Fprintf(buf, "%s%s\n", spacingPrefix,
+			strconv.FormatFloat(float64(spacing), 'f', -1, 32))
+	}
+	shared := dancer.Dancers{}
+	for r := 0; r < rows; r++ {
+		tokens := []string{}
+		for c := 0; c < columns; c++ {
+			token := "."
+			switch ds := grid[[2]int{ r, c }]; len(ds) {
+			case 0:
+			case 1:
+				token = formatDancer(ds[0])
+			default:
+				token = sharedMark
+				shared = append(shared, ds...)
+			}
+			tokens = append(tokens, fmt.Sprintf("%-2s", token))
+		}
+		fmt.Fprintln(buf, strings.TrimRight(strings.Join(tokens, " "), " "))
+	}
+	if len(shared) > 0 || len(offGrid) > 0 {
+		return buf.String(), &ErrInexact{
+			Spacing: spacing,
+			Shared: shared,
+			OffGrid: offGrid,
+		}
+	}
+	return buf.String(), nil
+}
+
+// onGrid returns the nearest grid index to the distance and whether
+// the distance is close enough to it.
+func onGrid(distance float32, spacing float32) (int, bool) {
+	f := float64(distance / spacing)
+	i := math.Round(f)
+	return int(i), math.Abs(f - i) < gridTolerance
+}
+
+func formatDancer(d dancer.Dancer) string {
+	m, ok := marks[d.Gender()]
+	if !ok {
+		m = marks[dancer.Unspecified]
+	}
+	quarter := int(math.Round(float64(d.Direction()) * 4))
+	quarter = (quarter % 4 + 4) % 4
+	token := m[quarter:quarter + 1]
+	if d.CoupleNumber() > 0 {
+		token = strconv.Itoa(d.CoupleNumber()) + token
+	}
+	return token
+}
@@ -0,0 +1,156 @@
+package notation
+
+import "errors"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+func TestParseSquaredSet(t *testing.T) {
+	dancers, err := Parse(`
+# A squared set
+.  1d 1v .
+2> .  .  4l
+2r .  .  4<
+.  3^ 3u .
+`)
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	set := dancer.NewSquaredSet(4).Dancers()
+	if want, got := len(set), len(dancers); got != want {
+		t.Fatalf("Wrong number of dancers: want %d, got %d", want, got)
+	}
+	for i, want := range set {
+		got := dancers[i]
+		if got.Ordinal() != want.Ordinal() ||
+			got.CoupleNumber() != want.CoupleNumber() ||
+			got.Gender() != want.Gender() ||
+			!got.Position().Equal(want.Position()) ||
+			!got.Direction().Equal(want.Direction()) {
+			t.Errorf("Dancer %d: want %v %v %v, got %v %v %v", i,
+				want, want.Position(), want.Direction(),
+				got, got.Position(), got.Direction())
+		}
+		if got.OriginalPartner() != dancers[i ^ 1] {
+			t.Errorf("Wrong OriginalPartner for %v", got)
+		}
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, diagram := range []string{
+		".  1d 1v .\n2> .  .  4l\n2r .  .  4<\n.  3^ 3u .\n",
+		"# spacing 0.5\n.  v  .\n>  .  <\n.  ^  .\n",
+		"1v 1d\n.  .\nU  L\n",
+	} {
+		dancers, err := Parse(diagram)
+		if err != nil {
+			t.Errorf("Parse %q: %s", diagram, err)
+			continue
+		}
+		got, err := Format(dancers)
+		if err != nil {
+			t.Errorf("Format %q: %s", diagram, err)
+		}
+		if got != diagram {
+			t.Errorf("Round trip:\nwant\n%s\ngot\n%s", diagram, got)
+		}
+	}
+}
+
+func TestFormatRotated(t *testing.T) {
+	dancers := MustParse("1v\n1u")
+	dancers[0].Move(geometry.NewPositionDownLeft(0, 0), geometry.Direction(0.2))
+	dancers[1].Move(geometry.NewPositionDownLeft(0, 1), geometry.Direction(0.55))
+	got, err := Format(dancers)
+	if err != nil {
+		t.Errorf("Format: %s", err)
+	}
+	if want := "1> 1u\n"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestFormatInexact(t *testing.T) {
+	// Two dancers 0.1 apart are closer together than the finest
+	// spacing:
+	dancers := MustParse("1v 1d\n")
+	dancers[0].Move(geometry.NewPositionDownLeft(0, 0), dancers[0].Direction())
+	dancers[1].Move(geometry.NewPositionDownLeft(0, 0.1), dancers[1].Direction())
+	got, err := Format(dancers)
+	var inexact *ErrInexact
+	if !errors.As(err, &inexact) {
+		t.Fatalf("Expected ErrInexact, got %v", err)
+	}
+	if want, got := "Dancer_1Gal", dancerNames(inexact.OffGrid); got != want {
+		t.Errorf("Wrong OffGrid dancers: want %s, got %s", want, got)
+	}
+	if want := "# spacing 0.125\n1v 1d\n"; got != want {
+		t.Errorf("Wrong diagram: want %q, got %q", want, got)
+	}
+	t.Logf("%s", err)
+	// With a third dancer further away, the two are nearest to the
+	// same cell:
+	dancers = MustParse("1v 1d 2v\n")
+	dancers[0].Move(geometry.NewPositionDownLeft(0, 0.45), dancers[0].Direction())
+	dancers[1].Move(geometry.NewPositionDownLeft(0, 0.55), dancers[1].Direction())
+	dancers[2].Move(geometry.NewPositionDownLeft(0, 0), dancers[2].Direction())
+	got, err = Format(dancers)
+	if !errors.As(err, &inexact) {
+		t.Fatalf("Expected ErrInexact, got %v", err)
+	}
+	if want, got := "Dancer_1Guy, Dancer_1Gal", dancerNames(inexact.Shared); got != want {
+		t.Errorf("Wrong Shared dancers: want %s, got %s", want, got)
+	}
+	if want := "# spacing 0.125\n2v .  .  .  *\n"; got != want {
+		t.Errorf("Wrong diagram: want %q, got %q", want, got)
+	}
+	if _, err := Parse(got); err == nil {
+		t.Errorf("A diagram with shared cells shouldn't parse")
+	}
+	t.Logf("%s", err)
+}
+
+func TestParseDancers(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	setJSON, err := set.(*dancer.SetImpl).MarshalJSON()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	dancersJSON, err := set.Dancers().MarshalJSON()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want, err := Format(set.Dancers())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, input := range []string{ string(setJSON), string(dancersJSON), want } {
+		dancers, err := ParseDancers([]byte(input))
+		if err != nil {
+			t.Errorf("%s", err)
+			continue
+		}
+		if got, _ := Format(dancers); got != want {
+			t.Errorf("Wrong dancers from %q:\nwant\n%s\ngot\n%s", input, want, got)
+		}
+	}
+	if _, err := ParseDancers([]byte(`[]`)); err == nil {
+		t.Errorf("Dancers without a version should be rejected")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, diagram := range []string{
+		"1v x",
+		"0v",
+		"1v 1>",
+		"# spacing -1\n1v",
+	} {
+		_, err := Parse(diagram)
+		if _, ok := err.(*ErrSyntax); !ok {
+			t.Errorf("Parse %q: expected ErrSyntax, got %v", diagram, err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Package raster draws the dancers of a Timeline as PNG frames or as
+// an animated GIF, using only the standard image packages.
+//
+// As in package svg, a Position's Left coordinate is drawn along the x
+// axis and its Down coordinate along the y axis.  Guys are squares and
+// gals are circles, colored by couple number, each with a nose on the
+// side it is facing.
+package raster
+
+import "fmt"
+import "image"
+import "image/color"
+import "image/gif"
+import "image/png"
+import "io"
+import "math"
+import "os"
+import "path/filepath"
+import "time"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+
+
+// DefaultScale is the number of pixels per geometry.CoupleDistance if
+// neither Options.Width nor Options.Height is specified.
+const DefaultScale = 40
+
+// DefaultFrameRate is the default value of Options.FrameRate.
+const DefaultFrameRate = 10
+
+// DefaultTickDuration is how long each timeline.Time lasts if
+// Options.TickDuration is 0.  It is one beat at 120 beats per minute.
+const DefaultTickDuration = 500 * time.Millisecond
+
+// Sizes of things, in the same units as geometry.Position:
+const (
+	dancerSize = 0.6
+	noseSize = 0.12
+	outline = 0.04
+	margin = 0.75
+)
+
+
+// Options control how a Timeline is drawn.
+type Options struct {
+	// Width and Height are the size of each frame in pixels.  If
+	// only one is specified the other is chosen to fit the
+	// Timeline's Bounds.  If neither is then DefaultScale is used.
+	Width, Height int
+	// FrameRate is the number of frames per second.
+	FrameRate float64
+	// TickDuration is how long each timeline.Time lasts.
+	TickDuration time.Duration
+}
+
+
+// Palette indices:
+const (
+	backgroundIndex = iota
+	outlineIndex
+	firstCoupleIndex
+)
+
+// palette is used for every frame, so that an animated GIF needs no
+// local color tables.
+var palette = color.Palette{
+	color.RGBA{ 0x77, 0x88, 0x99, 0xff },     // lightslategray
+	color.Black,
+	color.RGBA{ 0xff, 0xff, 0xff, 0xff },     // dancers with no couple number
+	color.RGBA{ 0xe0, 0x40, 0x40, 0xff },
+	color.RGBA{ 0x40, 0x80, 0xe0, 0xff },
+	color.RGBA{ 0x40, 0xc0, 0x40, 0xff },
+	color.RGBA{ 0xf0, 0xd0, 0x30, 0xff },
+	color.RGBA{ 0xc0, 0x60, 0xd0, 0xff },
+	color.RGBA{ 0xf0, 0x90, 0x30, 0xff },
+}
+
+func dancerColorIndex(d dancer.Dancer) uint8 {
+	colors := len(palette) - firstCoupleIndex - 1
+	if d.CoupleNumber() <= 0 {
+		return firstCoupleIndex
+	}
+	return uint8(firstCoupleIndex + 1 + (d.CoupleNumber() - 1) % colors)
+}
+
+
+// frame maps floor positions to pixels.
+type frame struct {
+	width, height int
+	scale float64
+	// minX and minY are the floor coordinates of the top left
+	// corner of the frame.
+	minX, minY float64
+}
+
+func newFrame(tl timeline.Timeline, options Options) *frame {
+	left, right, down, up := tl.Bounds()
+	minX, maxX := float64(right) - margin, float64(left) + margin
+	minY, maxY := float64(up) - margin, float64(down) + margin
+	floorWidth, floorHeight := maxX - minX, maxY - minY
+	f := &frame{}
+	switch {
+	case options.Width > 0 && options.Height > 0:
+		f.scale = math.Min(float64(options.Width) / floorWidth,
+			float64(options.Height) / floorHeight)
+	case options.Width > 0:
+		f.scale = float64(options.Width) / floorWidth
+	case options.Height > 0:
+		f.scale = float64(options.Height) / floorHeight
+	default:
+		f.scale = DefaultScale
+	}
+	f.width, f.height = options.Width, options.Height
+	if f.width <= 0 {
+		f.width = int(math.Ceil(floorWidth * f.scale))
+	}
+	if f.height <= 0 {
+		f.height = int(math.Ceil(floorHeight * f.scale))
+	}
+	// Center the floor in the frame:
+	f.minX = (minX + maxX) / 2 - float64(f.width) / f.scale / 2
+	f.minY = (minY + maxY) / 2 - float64(f.height) / f.scale / 2
+	return f
+}
+
+// floor returns the floor coordinates of the center of a pixel.
+func (f *frame) floor(px, py int) (float64, float64) {
+	return f.minX + (float64(px) + 0.5) / f.scale,
+		f.minY + (float64(py) + 0.5) / f.scale
+}
+
+// pixels returns the range of pixels which might be within radius of
+// the floor coordinates x, y.
+func (f *frame) pixels(x, y, radius float64) image.Rectangle {
+	r := image.Rect(
+		int(math.Floor((x - radius - f.minX) * f.scale)),
+		int(math.Floor((y - radius - f.minY) * f.scale)),
+		int(math.Ceil((x + radius - f.minX) * f.scale)) + 1,
+		int(math.Ceil((y + radius - f.minY) * f.scale)) + 1)
+	return r.Intersect(image.Rect(0, 0, f.width, f.height))
+}
+
+// fill sets the pixels within radius of x, y for which inside is true
+// to the color with the specified index.
+func (f *frame) fill(img *image.Paletted, x, y, radius float64, index uint8, inside func(dx, dy float64) bool) {
+	r := f.pixels(x, y, radius)
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			fx, fy := f.floor(px, py)
+			if inside(fx - x, fy - y) {
+				img.SetColorIndex(px, py, index)
+			}
+		}
+	}
+}
+
+func circle(radius float64) func(dx, dy float64) bool {
+	return func(dx, dy float64) bool {
+		return dx * dx + dy * dy <= radius * radius
+	}
+}
+
+// square returns a test for a square with the specified half width,
+// whose sides are parallel and perpendicular to direction.
+func square(halfWidth float64, direction geometry.Direction) func(dx, dy float64) bool {
+	angle := float64(direction) * 2 * math.Pi
+	sin, cos := math.Sin(angle), math.Cos(angle)
+	return func(dx, dy float64) bool {
+		// Rotate into the dancer's frame of reference:
+		u := dx * cos - dy * sin
+		v := dx * sin + dy * cos
+		return math.Abs(u) <= halfWidth && math.Abs(v) <= halfWidth
+	}
+}
+
+func (f *frame) drawDancer(img *image.Paletted, d dancer.Dancer, p geometry.Position, direction geometry.Direction) {
+	x, y := float64(p.Left), float64(p.Down)
+	nose := p.Add(geometry.NewPosition(direction, dancerSize / 2))
+	f.fill(img, float64(nose.Left), float64(nose.Down), noseSize, outlineIndex, circle(noseSize))
+	half := dancerSize / 2.0
+	switch d.Gender() {
+	case dancer.Guy:
+		f.fill(img, x, y, half * math.Sqrt2, outlineIndex, square(half, direction))
+		f.fill(img, x, y, half * math.Sqrt2, dancerColorIndex(d), square(half - outline, direction))
+	default:
+		f.fill(img, x, y, half, outlineIndex, circle(half))
+		f.fill(img, x, y, half, dancerColorIndex(d), circle(half - outline))
+	}
+}
+
+// draw returns an image of the dancers of the Timeline at time t.
+func (f *frame) draw(tl timeline.Timeline, t float32) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, f.width, f.height), palette)
+	// image.NewPaletted fills with index 0, backgroundIndex.
+	for _, d := range tl.Dancers() {
+		p, direction, ok := timeline.Interpolate(tl, d, t)
+		if !ok {
+			p, direction = d.Position(), d.Direction()
+		}
+		f.drawDancer(img, d, p, direction)
+	}
+	return img
+}
+
+
+func (options *Options) defaults() {
+	if options.FrameRate <= 0 {
+		options.FrameRate = DefaultFrameRate
+	}
+	if options.TickDuration <= 0 {
+		options.TickDuration = DefaultTickDuration
+	}
+}
+
+// frameTimes returns the timeline.Times, possibly fractional, at which
+// frames are drawn: from the first snapshot to the last at
+// options.FrameRate.
+func frameTimes(tl timeline.Timeline, options Options) []float32 {
+	times := timeline.Times(tl)
+	if len(times) == 0 {
+		return []float32{ 0 }
+	}
+	first, last := float64(times[0]), float64(times[len(times) - 1])
+	ticksPerFrame := 1 / (options.FrameRate * options.TickDuration.Seconds())
+	count := int(math.Floor((last - first) / ticksPerFrame + 0.0001)) + 1
+	result := []float32{}
+	for i := 0; i < count; i++ {
+		result = append(result, float32(first + float64(i) * ticksPerFrame))
+	}
+	if result[len(result) - 1] < float32(last) {
+		result = append(result, float32(last))
+	}
+	return result
+}
+
+// Frames returns the frames of an animation of the Timeline.
+func Frames(tl timeline.Timeline, options Options) []*image.Paletted {
+	options.defaults()
+	f := newFrame(tl, options)
+	frames := []*image.Paletted{}
+	for _, t := range frameTimes(tl, options) {
+		frames = append(frames, f.draw(tl, t))
+	}
+	return frames
+}
+
+// Frame returns an image of the dancers of the Timeline at time t.
+func Frame(tl timeline.Timeline, t float32, options Options) *image.Paletted {
+	options.defaults()
+	return newFrame(tl, options).draw(tl, t)
+}
+
+// WritePNG writes an image of the dancers of the Timeline at time t as
+// a PNG.
+func WritePNG(w io.Writer, tl timeline.Timeline, t float32, options Options) error {
+	return png.Encode(w, Frame(tl, t, options))
+}
+
+// WritePNGFrames writes each of the Frames of the Timeline as a PNG
+// file in the directory dir.  The files are named by prefix and the
+// frame number.  It returns the names of the files.
+func WritePNGFrames(dir, prefix string, tl timeline.Timeline, options Options) ([]string, error) {
+	files := []string{}
+	for i, img := range Frames(tl, options) {
+		name := filepath.Join(dir, fmt.Sprintf("%s%04d.png", prefix, i))
+		f, err := os.Create(name)
+		if err != nil {
+			return files, err
+		}
+		err = png.Encode(f, img)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// WriteGIF writes an animated GIF of the Timeline.  The animation
+// loops forever.
+func WriteGIF(w io.Writer, tl timeline.Timeline, options Options) error {
+	options.defaults()
+	frames := Frames(tl, options)
+	// GIF delays are in hundredths of a second:
+	delay := int(math.Round(100 / options.FrameRate))
+	anim := &gif.GIF{}
+	for _, img := range frames {
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	return gif.EncodeAll(w, anim)
+}
@@ -0,0 +1,96 @@
+package raster
+
+import "bytes"
+import "image/gif"
+import "image/png"
+import "os"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+
+
+func makeTimeline() (dancer.Set, timeline.Timeline) {
+	set := dancer.NewSquaredSet(4)
+	tl := timeline.NewTimeline(set.Dancers())
+	tl.MakeSnapshot(0)
+	for _, d := range set.Dancers() {
+		d.Move(d.Position().Add(geometry.NewPosition(d.Direction(), 1)),
+			d.Direction().QuarterLeft())
+	}
+	tl.MakeSnapshot(2)
+	return set, tl
+}
+
+func TestFrames(t *testing.T) {
+	_, tl := makeTimeline()
+	frames := Frames(tl, Options{ Width: 200 })
+	// Two ticks of half a second at ten frames per second, and
+	// the last frame:
+	if want, got := 11, len(frames); got != want {
+		t.Fatalf("Wrong number of frames: want %d, got %d", want, got)
+	}
+	if want, got := 200, frames[0].Bounds().Dx(); got != want {
+		t.Errorf("Wrong width: want %d, got %d", want, got)
+	}
+	if frames[0].Bounds().Dy() != frames[0].Bounds().Dx() {
+		t.Errorf("A squared set should fit in a square frame: %v", frames[0].Bounds())
+	}
+	// The center of the set is empty at the start:
+	center := frames[0].Bounds().Max.Div(2)
+	if got := frames[0].ColorIndexAt(center.X, center.Y); got != backgroundIndex {
+		t.Errorf("Center of the set isn't empty: %d", got)
+	}
+}
+
+func TestFrameColors(t *testing.T) {
+	set, tl := makeTimeline()
+	f := newFrame(tl, Options{})
+	img := f.draw(tl, 0)
+	for _, d := range set.Dancers() {
+		p, _, _ := timeline.Interpolate(tl, d, 0)
+		px := int((float64(p.Left) - f.minX) * f.scale)
+		py := int((float64(p.Down) - f.minY) * f.scale)
+		if want, got := dancerColorIndex(d), img.ColorIndexAt(px, py); got != want {
+			t.Errorf("Wrong color for %v: want %d, got %d", d, want, got)
+		}
+	}
+}
+
+func TestWriteGIF(t *testing.T) {
+	_, tl := makeTimeline()
+	buf := bytes.NewBuffer(nil)
+	if err := WriteGIF(buf, tl, Options{ FrameRate: 4, Height: 100 }); err != nil {
+		t.Fatalf("WriteGIF: %s", err)
+	}
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatalf("DecodeAll: %s", err)
+	}
+	if want, got := 5, len(anim.Image); got != want {
+		t.Errorf("Wrong number of frames: want %d, got %d", want, got)
+	}
+	if want, got := 25, anim.Delay[0]; got != want {
+		t.Errorf("Wrong delay: want %d, got %d", want, got)
+	}
+}
+
+func TestWritePNGFrames(t *testing.T) {
+	_, tl := makeTimeline()
+	// One frame every other tick:
+	files, err := WritePNGFrames(t.TempDir(), "frame", tl, Options{ FrameRate: 1 })
+	if err != nil {
+		t.Fatalf("WritePNGFrames: %s", err)
+	}
+	if want, got := 2, len(files); got != want {
+		t.Fatalf("Wrong number of files: want %d, got %d", want, got)
+	}
+	f, err := os.Open(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := png.Decode(f); err != nil {
+		t.Errorf("Bad PNG: %s", err)
+	}
+}
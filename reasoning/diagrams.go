@@ -5,11 +5,12 @@ package reasoning
 
 import "fmt"
 import "os"
+import "path/filepath"
 import "reflect"
 import "sort"
 import "html/template"
 import "squaredance/dancer"
-import "squaredance/geometry"
+import "squaredance/svg"
 
 
 // MergeTemplateFuncs adds the elements from tfm2 into tfm1 and returns tfm1.
@@ -21,15 +22,10 @@ func MergeTemplateFuncs (tfm1, tfm2 template.FuncMap)  template.FuncMap {
 }
 
 var DancerTemplateFunctions = template.FuncMap{
-	"JSDirection": func(d geometry.Direction) float32 {
-		return float32(d) * 4
-	},
-	"JSGender": func (gender dancer.Gender) string {
-		switch gender {
-		case dancer.Guy: return "guy"
-		case dancer.Gal: return "gal"
-		default: return "unspecified"
-		}
+	// DancersSVG draws the dancers as an inline svg element with
+	// the specified id.
+	"DancersSVG": func(id string, dancers dancer.Dancers) template.HTML {
+		return template.HTML(svg.Render(dancers, svg.Options{ ID: id }))
 	},
 }
 
@@ -102,8 +98,10 @@ func (fts FormationTypeSort) Less (i, j int) bool {
 }
 
 
-func WriteFormationDiagrams() error {
-	filename := "formation_types.html"
+// WriteFormationDiagrams writes formation_types.html, a diagram of a
+// sample of each FormationType, in the directory dir.
+func WriteFormationDiagrams(dir string) error {
+	filename := filepath.Join(dir, "formation_types.html")
 	// Sort
 	fts := FormationTypeSort{}
 	for _, ft := range AllFormationTypes {
@@ -144,21 +142,6 @@ td {
   vertical-align: middle;
 }
     </style>
-    <script type="text/javascript"
-            src="https://marknahabedian.github.io/SquareDanceFormationDiagrams/dancers.js">
-    </script>
-    <script type="text/javascript">
-function contentLoaded() {
-  {{- range .}}
-  // {{.Name}}
-    {{if .HasSample -}}
-      {{- template "DancersSVGTemplate" . -}}
-    {{end -}}
-  {{- end -}}
-}
-
-document.addEventListener("DOMContentLoaded", contentLoaded, false);
-    </script>
   </head>
   <body>
     <h1>
@@ -185,7 +168,7 @@ document.addEventListener("DOMContentLoaded", contentLoaded, false);
             <td>{{.Name}}</td>
             <td>
               {{- if .HasSample -}}
-                <svg id="{{.SVGId}}"></svg>
+                {{- template "DancersSVGTemplate" . -}}
               {{- end -}}
             </td>
           </tr>
@@ -196,13 +179,11 @@ document.addEventListener("DOMContentLoaded", contentLoaded, false);
 </html>
 ` + DancersSVGTemplate))
 
+// DancersSVGTemplate defines a template which draws .Sample.Dancers
+// as an inline svg element whose id is .SVGId.  It needs the
+// DancerTemplateFunctions.
 const DancersSVGTemplate = `
 {{define "DancersSVGTemplate"}}
-new Floor([
-  {{- range .Sample.Dancers -}}
-    new Dancer({{.Position.Left}}, {{.Position.Down}}, {{JSDirection .Direction}}, "{{.Ordinal}}",
-               {{JSGender .Gender}}, "white", "{{.Ordinal}}"),
-      {{end -}}
-    ]).draw("{{.SVGId}}");
+  {{- DancersSVG .SVGId .Sample.Dancers -}}
 {{end}}
 `
@@ -2,6 +2,7 @@ package reasoning
 
 import "fmt"
 import "reflect"
+import "defimpl/runtime"
 import "squaredance/dancer"
 
 // Formation represents a square dance formation.
@@ -24,12 +25,56 @@ type FormationType = reflect.Type
 // implementation type.
 var AllFormationTypes map[string] FormationType = make(map[string] FormationType)
 
-func LookupFormationType(name string) FormationType {
+// ErrUnknownFormation is returned when there is no FormationType
+// with the requested name.
+type ErrUnknownFormation struct {
+	Name string
+}
+
+func (e *ErrUnknownFormation) Error() string {
+	return fmt.Sprintf("No formation named %q", e.Name)
+}
+
+// LookupFormationType returns the FormationType with the specified
+// name.
+func LookupFormationType(name string) (FormationType, error) {
 	ft, ok := AllFormationTypes[name]
 	if !ok {
-		panic(fmt.Sprintf("No formation named %q", name))
+		return nil, &ErrUnknownFormation{ Name: name }
+	}
+	return FormationType(ft), nil
+}
+
+// MustLookupFormationType is like LookupFormationType but panics if
+// there is no such FormationType.  It is intended for names which
+// are fixed in the source code.
+func MustLookupFormationType(name string) FormationType {
+	ft, err := LookupFormationType(name)
+	if err != nil {
+		panic(err)
+	}
+	return ft
+}
+
+// FormationTypeOf returns the FormationType of the Formation, or nil
+// if it can't be determined.
+func FormationTypeOf(f Formation) FormationType {
+	ft, _ := runtime.InterfaceFor(reflect.TypeOf(f))
+	return ft
+}
+
+// FormationName returns the name of the Formation's FormationType,
+// preceded by its Handedness if it has one, as in
+// "RightHanded MiniWave".
+func FormationName(f Formation) string {
+	name := fmt.Sprintf("%T", f)
+	if ft := FormationTypeOf(f); ft != nil {
+		name = ft.Name()
+	}
+	if h, ok := f.(interface{ Handedness() Handedness }); ok {
+		name = fmt.Sprintf("%s %s", h.Handedness(), name)
 	}
-	return FormationType(ft)
+	return name
 }
 
 func init() {
@@ -1,5 +1,7 @@
 package reasoning
 
+import "fmt"
+
 
 // Handedness represents the handedness of a square dance formation.
 type Handedness int
@@ -17,7 +19,7 @@ func (h Handedness) String() string {
 		case RightHanded: return "RightHanded"
 		case LeftHanded: return "LeftHanded"
 	}
-	panic("Unsupported handedness")
+	return fmt.Sprintf("Handedness(%d)", int(h))
 }
 
 func (h Handedness) Opposite() Handedness {
@@ -0,0 +1,126 @@
+// Saving and loading recognized Formations as JSON.
+
+package reasoning
+
+import "encoding/json"
+import "fmt"
+import "reflect"
+import "squaredance/dancer"
+
+
+// FormationJSON is the JSON encoding of a Formation: the name of its
+// FormationType and the Ordinals of its dancers, in the order that
+// the Formation's Dancers method returns them, which is the order of
+// the Formation's slots.
+type FormationJSON struct {
+	Type string                  `json:"type"`
+	Dancers []int                `json:"dancers"`
+}
+
+// FormationsJSON is the JSON encoding of some Formations.
+type FormationsJSON struct {
+	Version int                  `json:"version"`
+	Formations []FormationJSON   `json:"formations"`
+}
+
+// NewFormationJSON returns the JSON encoding of the Formation.
+func NewFormationJSON(f Formation) (FormationJSON, error) {
+	ft := FormationTypeOf(f)
+	if ft == nil {
+		return FormationJSON{}, fmt.Errorf("Unknown FormationType for %T", f)
+	}
+	fj := FormationJSON{
+		Type: ft.Name(),
+		Dancers: []int{},
+	}
+	for _, d := range f.Dancers() {
+		fj.Dancers = append(fj.Dancers, d.Ordinal())
+	}
+	return fj, nil
+}
+
+// FormationFromJSON returns the Formation of the dancers that fj
+// describes.  The dancers must still be in that Formation.
+func FormationFromJSON(fj FormationJSON, dancers dancer.Dancers) (Formation, error) {
+	ft, err := LookupFormationType(fj.Type)
+	if err != nil {
+		return nil, err
+	}
+	byOrdinal := map[int]dancer.Dancer{}
+	for _, d := range dancers {
+		byOrdinal[d.Ordinal()] = d
+	}
+	members := dancer.Dancers{}
+	for _, ordinal := range fj.Dancers {
+		d, ok := byOrdinal[ordinal]
+		if !ok {
+			return nil, fmt.Errorf("No dancer with ordinal %d for %s", ordinal, fj.Type)
+		}
+		members = append(members, d)
+	}
+	if ft.Kind() == reflect.Slice {
+		return members, nil
+	}
+	if ft == MustLookupFormationType("Dancer") {
+		if len(members) != 1 {
+			return nil, fmt.Errorf("A Dancer formation has one dancer, not %d", len(members))
+		}
+		return members[0], nil
+	}
+	found, _ := FindFormations(members, ft)
+	for _, f := range found {
+		if sameOrder(f.Dancers(), members) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("Dancers %v are not in a %s", fj.Dancers, fj.Type)
+}
+
+func sameOrder(ds1, ds2 dancer.Dancers) bool {
+	if len(ds1) != len(ds2) {
+		return false
+	}
+	for i, d := range ds1 {
+		if d != ds2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalFormations encodes the Formations as FormationsJSON.
+func MarshalFormations(formations []Formation) ([]byte, error) {
+	fsj := FormationsJSON{
+		Version: dancer.JSONVersion,
+		Formations: []FormationJSON{},
+	}
+	for _, f := range formations {
+		fj, err := NewFormationJSON(f)
+		if err != nil {
+			return nil, err
+		}
+		fsj.Formations = append(fsj.Formations, fj)
+	}
+	return json.Marshal(fsj)
+}
+
+// UnmarshalFormations decodes Formations of the dancers that were
+// encoded by MarshalFormations.
+func UnmarshalFormations(data []byte, dancers dancer.Dancers) ([]Formation, error) {
+	fsj := FormationsJSON{}
+	if err := json.Unmarshal(data, &fsj); err != nil {
+		return nil, err
+	}
+	if err := dancer.CheckJSONVersion(fsj.Version); err != nil {
+		return nil, err
+	}
+	formations := []Formation{}
+	for _, fj := range fsj.Formations {
+		f, err := FormationFromJSON(fj, dancers)
+		if err != nil {
+			return nil, err
+		}
+		formations = append(formations, f)
+	}
+	return formations, nil
+}
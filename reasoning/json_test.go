@@ -0,0 +1,36 @@
+package reasoning
+
+import "testing"
+
+
+func TestFormationsJSON(t *testing.T) {
+	sample := MakeSampleFormation(MustLookupFormationType("BoxOfFour"))
+	if sample == nil {
+		t.Skip("No sample BoxOfFour")
+	}
+	dancers := sample.Dancers()
+	formations := Recognize(dancers)
+	data, err := MarshalFormations(formations)
+	if err != nil {
+		t.Fatalf("MarshalFormations: %s", err)
+	}
+	decoded, err := UnmarshalFormations(data, dancers)
+	if err != nil {
+		t.Fatalf("UnmarshalFormations %s: %s", data, err)
+	}
+	if len(decoded) != len(formations) {
+		t.Fatalf("want %d formations, got %d", len(formations), len(decoded))
+	}
+	for i, f := range formations {
+		if FormationTypeOf(f) != FormationTypeOf(decoded[i]) ||
+			!sameOrder(f.Dancers(), decoded[i].Dancers()) {
+			t.Errorf("want %v, got %v", f, decoded[i])
+		}
+	}
+	if _, err := FormationFromJSON(FormationJSON{
+		Type: "MiniWave",
+		Dancers: []int{ 0, 0 },
+	}, dancers); err == nil {
+		t.Errorf("Bogus MiniWave should fail")
+	}
+}
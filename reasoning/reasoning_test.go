@@ -21,7 +21,7 @@ func showAllAssertions(t *testing.T, root rete.Node) {
 }
 
 func TestWriteDiagrams(t *testing.T) {
-	if err := WriteFormationDiagrams(); err != nil {
+	if err := WriteFormationDiagrams("."); err != nil {
 		t.Errorf("WriteFormationDiagrams: %s", err)
 	}
 }
@@ -0,0 +1,126 @@
+package reasoning
+
+import "reflect"
+import "sort"
+import "squaredance/dancer"
+
+
+// Recognize describes the dancers by the Formations that they are in.
+// Larger Formations are preferred.  No dancer is in more than one of
+// the returned Formations.  Dancers that aren't in any Formation of
+// two or more dancers aren't described.
+func Recognize(dancers dancer.Dancers) []Formation {
+	if len(dancers) == 0 {
+		return []Formation{}
+	}
+	type candidate struct {
+		ft FormationType
+		size int
+	}
+	candidates := []candidate{}
+	for _, ft := range AllFormationTypes {
+		if ft.Kind() == reflect.Slice {
+			continue
+		}
+		sample := MakeSampleFormation(ft)
+		if sample == nil || sample.NumberOfDancers() < 2 {
+			continue
+		}
+		candidates = append(candidates, candidate{ ft, sample.NumberOfDancers() })
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].size != candidates[j].size {
+			return candidates[i].size > candidates[j].size
+		}
+		return candidates[i].ft.Name() < candidates[j].ft.Name()
+	})
+	_, finder := FindFormations(dancers, candidates[0].ft)
+	covered := dancer.Dancers{}
+	result := []Formation{}
+	for _, c := range candidates {
+		found := []Formation{}
+		finder.DoFormations(c.ft, func(f Formation) {
+			found = append(found, f)
+		})
+		for _, f := range Cover(found, covered) {
+			covered = append(covered, f.Dancers()...)
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// Cover chooses from formations those which don't share any dancers
+// with each other or with exclude, covering as many dancers as
+// possible.  Among equally good choices it prefers the Formations
+// with the lowest Ordinals so that the result is deterministic.
+func Cover(formations []Formation, exclude dancer.Dancers) []Formation {
+	found := []Formation{}
+	available := map[dancer.Dancer]bool{}
+	for _, f := range formations {
+		if !overlaps(f, exclude) {
+			found = append(found, f)
+			for _, d := range f.Dancers() {
+				available[d] = true
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return lowestOrdinal(found[i]) < lowestOrdinal(found[j])
+	})
+	// Taking the first Formation that fits isn't good enough: in a
+	// wave the centers' MiniWave would leave the ends out.
+	chosen := dancer.Dancers{}
+	current := []Formation{}
+	cover := []Formation{}
+	var search func(start int)
+	search = func(start int) {
+		if len(chosen) > countDancers(cover) {
+			cover = append([]Formation{}, current...)
+		}
+		for i := start; i < len(found) && countDancers(cover) < len(available); i++ {
+			f := found[i]
+			if overlaps(f, chosen) {
+				continue
+			}
+			chosen = append(chosen, f.Dancers()...)
+			current = append(current, f)
+			search(i + 1)
+			current = current[:len(current) - 1]
+			chosen = chosen[:len(chosen) - f.NumberOfDancers()]
+		}
+	}
+	search(0)
+	return cover
+}
+
+// overlaps returns true if any of the Formation's Dancers are in
+// dancers.
+func overlaps(f Formation, dancers dancer.Dancers) bool {
+	for _, d := range f.Dancers() {
+		if dancers.HasDancer(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// countDancers returns the number of Dancers in the Formations.
+func countDancers(formations []Formation) int {
+	count := 0
+	for _, f := range formations {
+		count += f.NumberOfDancers()
+	}
+	return count
+}
+
+// lowestOrdinal returns the lowest Ordinal of the Formation's Dancers.
+func lowestOrdinal(f Formation) int {
+	lowest := -1
+	for _, d := range f.Dancers() {
+		if lowest < 0 || d.Ordinal() < lowest {
+			lowest = d.Ordinal()
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,44 @@
+package reasoning
+
+import "testing"
+import "squaredance/dancer"
+import "squaredance/notation"
+
+
+func TestRecognizeSquaredSet(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	formations := Recognize(set.Dancers())
+	seen := map[dancer.Dancer]bool{}
+	for _, f := range formations {
+		for _, d := range f.Dancers() {
+			if seen[d] {
+				t.Errorf("%s is in more than one formation: %v", d, formations)
+			}
+			seen[d] = true
+		}
+	}
+	if want, got := 8, len(seen); got != want {
+		t.Errorf("Not everyone was recognized: want %d, got %d: %v",
+			want, got, formations)
+	}
+}
+
+func TestCoverWave(t *testing.T) {
+	// The centers have the lowest Ordinals, so taking the first
+	// MiniWave found would pair them and leave the ends out:
+	dancers := notation.MustParse("2v 1^ 1d 2u\n")
+	found, _ := FindFormations(dancers, MustLookupFormationType("MiniWave"))
+	cover := Cover(found, dancer.Dancers{})
+	if want, got := 2, len(cover); got != want {
+		t.Fatalf("Wrong number of MiniWaves: want %d, got %d: %v", want, got, cover)
+	}
+	// Excluding one of them leaves only the other:
+	exclude := cover[0].Dancers()
+	rest := Cover(found, exclude)
+	if want, got := 1, len(rest); got != want {
+		t.Fatalf("Wrong number of MiniWaves: want %d, got %d: %v", want, got, rest)
+	}
+	if overlaps(rest[0], exclude) {
+		t.Errorf("Excluded dancers in %s", rest[0])
+	}
+}
@@ -0,0 +1,100 @@
+// Completing names.
+
+package repl
+
+import "os"
+import "path/filepath"
+import "strings"
+import "squaredance/action"
+import "squaredance/reasoning"
+
+
+func commandNames() []string {
+	names := []string{}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+func roleNames() []string {
+	names := []string{}
+	for _, r := range reasoning.Roles {
+		names = append(names, r.Name())
+	}
+	return names
+}
+
+func actionNames() []string {
+	names := []string{}
+	for _, a := range action.AllActions {
+		names = append(names, a.Name())
+	}
+	return names
+}
+
+// fileNames returns the names of the files which start with prefix.
+// Directory names end with a separator.
+func fileNames(prefix string) []string {
+	matches, _ := filepath.Glob(prefix + "*")
+	names := []string{}
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			m += string(filepath.Separator)
+		}
+		names = append(names, m)
+	}
+	return names
+}
+
+// Complete returns the ways the last word of line might be completed,
+// each as the whole completed line.  The first word can be a command,
+// a role or an action.  After roles can come more roles or an action.
+// The argument of a command like save is a file name.  Names are
+// matched ignoring case.
+func Complete(line string) []string {
+	start := strings.LastIndexAny(line, " \t") + 1
+	head, word := line[:start], line[start:]
+	words := strings.Fields(head)
+	var names []string
+	switch {
+	case len(words) == 0:
+		names = append(append(commandNames(), roleNames()...), actionNames()...)
+	case len(words) == 1 && findCommand(words[0]) != nil:
+		if findCommand(words[0]).argument != "" {
+			names = fileNames(word)
+		}
+	default:
+		for _, w := range words {
+			if reasoning.LookupRole(w) == nil {
+				return []string{}
+			}
+		}
+		names = append(roleNames(), actionNames()...)
+	}
+	result := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] || !strings.HasPrefix(strings.ToLower(name), strings.ToLower(word)) {
+			continue
+		}
+		seen[name] = true
+		result = append(result, head + name)
+	}
+	return result
+}
+
+// commonPrefix returns the longest prefix shared by all of the
+// strings.
+func commonPrefix(strs []string) string {
+	if len(strs) == 0 {
+		return ""
+	}
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix) - 1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,401 @@
+// Package repl is a read-eval-print loop for practicing choreography.
+//
+// It starts with a squared set of four couples.  Each line is either a
+// command or a call, written either as sequence.Call.String writes it,
+// as in "OriginalHeads Meet", or as Sd would, as in "heads meet".
+// After each call it shows a diagram of the dancers, in the notation
+// of package notation, the formations they are in and how close they
+// are to being resolved.  Calls can be undone and redone and the
+// session can be saved to a file.  On a terminal, Tab completes the
+// names of calls, roles and commands.
+package repl
+
+import "bufio"
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "os"
+import "strings"
+import "squaredance/dancer"
+import "squaredance/notation"
+import "squaredance/reasoning"
+import "squaredance/sd"
+import "squaredance/sequence"
+
+
+// Prompt is written before each line is read.
+const Prompt = "squaredance> "
+
+// ErrQuit is returned by Eval for the quit command.
+var ErrQuit = errors.New("quit")
+
+
+// Session is the state of a read-eval-print loop: the calls that have
+// been done, starting from a squared set, and those that have been
+// undone and can be redone.
+type Session struct {
+	set dancer.Set
+	calls sequence.Sequence
+	// undone is a stack of the calls that can be redone.  The last
+	// is the next to be redone.
+	undone sequence.Sequence
+}
+
+// NewSession returns a Session with a squared set of four couples.
+func NewSession() *Session {
+	return &Session{
+		set: dancer.NewSquaredSet(4),
+		calls: sequence.Sequence{},
+		undone: sequence.Sequence{},
+	}
+}
+
+// Set returns the Set whose dancers have done the Session's Calls.
+func (s *Session) Set() dancer.Set {
+	return s.set
+}
+
+// Calls returns the calls that have been done.
+func (s *Session) Calls() sequence.Sequence {
+	return append(sequence.Sequence{}, s.calls...)
+}
+
+// CanUndo returns true if there is a call to undo.
+func (s *Session) CanUndo() bool {
+	return len(s.calls) > 0
+}
+
+// CanRedo returns true if there is an undone call to redo.
+func (s *Session) CanRedo() bool {
+	return len(s.undone) > 0
+}
+
+// Do has the dancers do the Call.  If they can't, nothing changes
+// and the error is returned.  Doing a call forgets any undone calls.
+func (s *Session) Do(call sequence.Call) error {
+	if err := s.do(call); err != nil {
+		return err
+	}
+	s.undone = sequence.Sequence{}
+	return nil
+}
+
+func (s *Session) do(call sequence.Call) error {
+	if err := (sequence.Sequence{ call }).Apply(s.set.Dancers()); err != nil {
+		var illegal *sequence.ErrIllegalCall
+		if errors.As(err, &illegal) {
+			illegal.Index = len(s.calls)
+		}
+		return err
+	}
+	s.calls = append(s.calls, call)
+	return nil
+}
+
+// Undo takes back the most recent call.  The dancers are returned to
+// where they were by doing the remaining calls again from a squared
+// set.
+func (s *Session) Undo() error {
+	if !s.CanUndo() {
+		return fmt.Errorf("Nothing to undo")
+	}
+	last := s.calls[len(s.calls) - 1]
+	if err := s.replay(s.calls[:len(s.calls) - 1]); err != nil {
+		return err
+	}
+	s.undone = append(s.undone, last)
+	return nil
+}
+
+// Redo does the most recently undone call again.
+func (s *Session) Redo() error {
+	if !s.CanRedo() {
+		return fmt.Errorf("Nothing to redo")
+	}
+	next := s.undone[len(s.undone) - 1]
+	if err := s.do(next); err != nil {
+		return err
+	}
+	s.undone = s.undone[:len(s.undone) - 1]
+	return nil
+}
+
+// Reset starts over from a squared set.  It can't be undone.
+func (s *Session) Reset() {
+	s.set = dancer.NewSquaredSet(4)
+	s.calls = sequence.Sequence{}
+	s.undone = sequence.Sequence{}
+}
+
+// replay does the calls from a squared set.
+func (s *Session) replay(calls sequence.Sequence) error {
+	set := dancer.NewSquaredSet(4)
+	if err := calls.Apply(set.Dancers()); err != nil {
+		return err
+	}
+	s.set = set
+	s.calls = append(sequence.Sequence{}, calls...)
+	return nil
+}
+
+// Save writes the Session's calls, one per line, as sd.ParseAnyCall
+// reads them.  Undone calls aren't saved.
+func (s *Session) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# squaredance session, %d calls\n", len(s.calls))
+	for _, call := range s.calls {
+		fmt.Fprintln(bw, call)
+	}
+	return bw.Flush()
+}
+
+// Load starts over and does the calls read from r, one per line, as
+// Save writes them.  Blank lines and lines starting with "#" are
+// ignored.  If any call fails the Session is left as it was.
+func (s *Session) Load(r io.Reader) error {
+	calls := sequence.Sequence{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber += 1
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		call, err := sd.ParseAnyCall(line)
+		if err != nil {
+			return fmt.Errorf("Line %d: %s", lineNumber, err)
+		}
+		calls = append(calls, call)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := s.replay(calls); err != nil {
+		return err
+	}
+	s.undone = sequence.Sequence{}
+	return nil
+}
+
+// Status describes where the dancers are: a diagram, the Formations
+// they are in and how close they are to being resolved.  If the
+// diagram can't show exactly where the dancers are then why is noted
+// after it.
+func (s *Session) Status() string {
+	buf := bytes.NewBufferString("")
+	dancers := s.set.Dancers()
+	diagram, err := notation.Format(dancers)
+	buf.WriteString(diagram)
+	if err != nil {
+		fmt.Fprintf(buf, "%s\n", err)
+	}
+	names := []string{}
+	for _, f := range reasoning.Recognize(dancers) {
+		names = append(names, reasoning.FormationName(f))
+	}
+	if len(names) == 0 {
+		names = append(names, "none recognized")
+	}
+	fmt.Fprintf(buf, "Formations: %s\n", strings.Join(names, ", "))
+	r := s.set.Resolve()
+	if r.Resolved() {
+		fmt.Fprintf(buf, "Resolved at %s", r.Point)
+		if len(r.Calls) > 0 {
+			fmt.Fprintf(buf, "; to get out, call %s", strings.Join(r.Calls, ", "))
+		}
+		fmt.Fprintln(buf)
+	} else {
+		fmt.Fprintf(buf, "Not resolved: %d couples paired, in sequence: %v\n",
+			r.Paired, r.InSequence)
+	}
+	return buf.String()
+}
+
+
+// command is a line that isn't a call.
+type command struct {
+	name string
+	// argument names the command's argument, if it takes one.
+	argument string
+	help string
+	run func(s *Session, out io.Writer, argument string) error
+}
+
+// commands are in the order that help lists them.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{ "help", "", "list the commands", func(s *Session, out io.Writer, argument string) error {
+			writeHelp(out)
+			return nil
+		}},
+		{ "show", "", "show where the dancers are", func(s *Session, out io.Writer, argument string) error {
+			_, err := io.WriteString(out, s.Status())
+			return err
+		}},
+		{ "calls", "", "list the calls done so far", func(s *Session, out io.Writer, argument string) error {
+			for i, call := range s.calls {
+				fmt.Fprintf(out, "%2d: %s\n", i + 1, call)
+			}
+			return nil
+		}},
+		{ "undo", "", "take back the last call", func(s *Session, out io.Writer, argument string) error {
+			return statusAfter(s, out, s.Undo())
+		}},
+		{ "redo", "", "do the last undone call again", func(s *Session, out io.Writer, argument string) error {
+			return statusAfter(s, out, s.Redo())
+		}},
+		{ "reset", "", "start over from a squared set", func(s *Session, out io.Writer, argument string) error {
+			s.Reset()
+			return statusAfter(s, out, nil)
+		}},
+		{ "save", "file", "save the calls to a file", func(s *Session, out io.Writer, argument string) error {
+			return saveFile(s, argument)
+		}},
+		{ "load", "file", "start over and do the calls in a file", func(s *Session, out io.Writer, argument string) error {
+			f, err := os.Open(argument)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return statusAfter(s, out, s.Load(f))
+		}},
+		{ "quit", "", "leave", func(s *Session, out io.Writer, argument string) error {
+			return ErrQuit
+		}},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func writeHelp(out io.Writer) {
+	fmt.Fprintln(out, "Enter a call, such as \"OriginalHeads Meet\" or \"heads meet\", or a command:")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-12s %s\n", strings.TrimSpace(c.name + " " + c.argument), c.help)
+	}
+	fmt.Fprintln(out, "Tab completes the names of calls, roles and commands.")
+}
+
+func statusAfter(s *Session, out io.Writer, err error) error {
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, s.Status())
+	return err
+}
+
+func saveFile(s *Session, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = s.Save(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Eval does what the line says and writes the result to out.  It
+// returns ErrQuit if the line is the quit command.  Other errors are
+// about the line and the loop can go on.
+func (s *Session) Eval(line string, out io.Writer) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	fields := strings.Fields(line)
+	if c := findCommand(fields[0]); c != nil {
+		argument := strings.TrimSpace(line[len(fields[0]):])
+		if c.argument != "" && argument == "" {
+			return fmt.Errorf("%s needs a %s", c.name, c.argument)
+		}
+		if c.argument == "" && argument != "" {
+			return fmt.Errorf("%s takes no argument", c.name)
+		}
+		return c.run(s, out, argument)
+	}
+	call, err := sd.ParseAnyCall(line)
+	if err != nil {
+		return err
+	}
+	return statusAfter(s, out, s.Do(call))
+}
+
+
+// LineReader is the source of the lines of a read-eval-print loop.
+type LineReader interface {
+	// ReadLine writes the prompt and returns the next line, without
+	// its line ending, or io.EOF if there are no more.
+	ReadLine(prompt string) (string, error)
+}
+
+type scannerLineReader struct {
+	scanner *bufio.Scanner
+	out io.Writer
+}
+
+// NewLineReader returns a LineReader which reads lines from in with
+// no editing.  The prompt is written to out.
+func NewLineReader(in io.Reader, out io.Writer) LineReader {
+	return &scannerLineReader{
+		scanner: bufio.NewScanner(in),
+		out: out,
+	}
+}
+
+func (lr *scannerLineReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(lr.out, prompt)
+	if !lr.scanner.Scan() {
+		if err := lr.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return lr.scanner.Text(), nil
+}
+
+// Loop reads lines from lr, evaluating each, until the quit command
+// or the end of the input.  Errors from evaluating a line are written
+// to out and the loop goes on.
+func (s *Session) Loop(lr LineReader, out io.Writer) error {
+	io.WriteString(out, s.Status())
+	for {
+		line, err := lr.ReadLine(Prompt)
+		if err == io.EOF {
+			fmt.Fprintln(out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = s.Eval(line, out)
+		if err == ErrQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(out, "%s\n", err)
+		}
+	}
+}
+
+// Run runs a read-eval-print loop on standard input and output.  If
+// standard input is a terminal then lines can be edited and Tab
+// completes names.
+func (s *Session) Run() error {
+	fmt.Fprintln(os.Stdout, "Type \"help\" for a list of commands.")
+	if editor, err := newTerminalEditor(os.Stdin, os.Stdout); err == nil {
+		return s.Loop(editor, os.Stdout)
+	}
+	return s.Loop(NewLineReader(os.Stdin, os.Stdout), os.Stdout)
+}
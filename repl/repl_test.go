@@ -0,0 +1,173 @@
+package repl
+
+import "bytes"
+import "errors"
+import "io"
+import "strings"
+import "testing"
+import "squaredance/notation"
+import "squaredance/sd"
+import "squaredance/sequence"
+
+
+func TestUndoRedo(t *testing.T) {
+	s := NewSession()
+	start := diagram(t, s)
+	if err := s.Eval("heads meet", io.Discard); err != nil {
+		t.Fatalf("%s", err)
+	}
+	met := diagram(t, s)
+	if met == start {
+		t.Fatalf("The heads didn't move")
+	}
+	if err := s.Eval("undo", io.Discard); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := diagram(t, s); got != start {
+		t.Errorf("Undo didn't go back to the start:\n%s", got)
+	}
+	if err := s.Eval("undo", io.Discard); err == nil {
+		t.Errorf("Expected an error undoing nothing")
+	}
+	if err := s.Eval("redo", io.Discard); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := diagram(t, s); got != met {
+		t.Errorf("Redo didn't do the call again:\n%s", got)
+	}
+	if s.CanRedo() {
+		t.Errorf("Nothing should be left to redo")
+	}
+	s.Undo()
+	if err := s.Do(mustParseCall(t, "OriginalSides QuarterLeft")); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if s.CanRedo() {
+		t.Errorf("Doing a call should forget undone calls")
+	}
+}
+
+func TestIllegalCall(t *testing.T) {
+	s := NewSession()
+	s.Eval("heads meet", io.Discard)
+	before := diagram(t, s)
+	err := s.Eval("PassToBacks", io.Discard)
+	var illegal *sequence.ErrIllegalCall
+	if !errors.As(err, &illegal) {
+		t.Fatalf("Expected ErrIllegalCall, got %v", err)
+	}
+	if want, got := 1, illegal.Index; got != want {
+		t.Errorf("Wrong index: want %d, got %d", want, got)
+	}
+	if got := diagram(t, s); got != before {
+		t.Errorf("The dancers moved:\n%s", got)
+	}
+	if want, got := 1, len(s.Calls()); got != want {
+		t.Errorf("Wrong number of calls: want %d, got %d", want, got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	s := NewSession()
+	for _, line := range []string{ "heads meet", "OriginalSides QuarterLeft" } {
+		if err := s.Eval(line, io.Discard); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	buf := bytes.NewBufferString("")
+	if err := s.Save(buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	saved := buf.String()
+	loaded := NewSession()
+	if err := loaded.Load(strings.NewReader(saved)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := diagram(t, s), diagram(t, loaded); got != want {
+		t.Errorf("Wrong dancers after Load:\nwant\n%s\ngot\n%s", want, got)
+	}
+	if err := loaded.Load(strings.NewReader("Frobnicate\n")); err == nil {
+		t.Errorf("Expected an error loading an unknown call")
+	}
+	if want, got := 2, len(loaded.Calls()); got != want {
+		t.Errorf("A failed Load changed the Session: want %d calls, got %d", want, got)
+	}
+}
+
+func TestLoop(t *testing.T) {
+	in := strings.NewReader("help\nheads meet\nfrobnicate\ncalls\nquit\nundo\n")
+	out := bytes.NewBufferString("")
+	s := NewSession()
+	if err := s.Loop(NewLineReader(in, out), out); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, want := range []string{ "Formations: FacingCouples", "Not resolved", "Unknown action", " 1: OriginalHeads Meet" } {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Output doesn't contain %q:\n%s", want, out)
+		}
+	}
+	if want, got := 1, len(s.Calls()); got != want {
+		t.Errorf("Lines after quit were evaluated")
+	}
+	t.Logf("%s", out)
+}
+
+func TestComplete(t *testing.T) {
+	for _, test := range []struct {
+		line string
+		want string
+	}{
+		{ "und", "undo" },
+		{ "OriginalHea", "OriginalHeads" },
+		{ "OriginalHeads me", "OriginalHeads Meet" },
+		{ "OriginalHeads PassTo", "OriginalHeads PassToBacks" },
+	} {
+		got := Complete(test.line)
+		if len(got) != 1 || got[0] != test.want {
+			t.Errorf("Complete(%q): want [%q], got %q", test.line, test.want, got)
+		}
+	}
+	if got := Complete("Meet Pa"); len(got) != 0 {
+		t.Errorf("Nothing should follow an action: %q", got)
+	}
+	if got := Complete("Quarter"); len(got) < 2 {
+		t.Errorf("Expected QuarterLeft and QuarterRight: %q", got)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	out := bytes.NewBufferString("")
+	// Tab completes "OriginalHea", a mistyped x is erased and Tab
+	// completes "Me".  Then the up arrow recalls that line.
+	in := strings.NewReader("OriginalHea\tx\x7fMe\t\r\x1b[A\r")
+	e := newEditor(in, out)
+	for _, want := range []string{ "OriginalHeads Meet ", "OriginalHeads Meet " } {
+		got, err := e.ReadLine("> ")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != want {
+			t.Errorf("Wrong line: want %q, got %q", want, got)
+		}
+	}
+	if _, err := e.ReadLine("> "); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func mustParseCall(t *testing.T, text string) sequence.Call {
+	call, err := sd.ParseAnyCall(text)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return call
+}
+
+func diagram(t *testing.T, s *Session) string {
+	t.Helper()
+	d, err := notation.Format(s.Set().Dancers())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return d
+}
@@ -0,0 +1,190 @@
+// Editing lines on a terminal.
+
+package repl
+
+import "bufio"
+import "fmt"
+import "io"
+import "os"
+import "os/exec"
+import "strings"
+
+
+// Control characters that the editor understands:
+const (
+	ctrlC = 0x03
+	ctrlD = 0x04
+	backspace = 0x08
+	tab = 0x09
+	ctrlU = 0x15
+	escape = 0x1b
+	del = 0x7f
+)
+
+// editor reads a line a character at a time, as from a terminal in
+// raw mode, echoing what is typed.  Tab completes the last word, and
+// the up and down arrows recall earlier lines.
+type editor struct {
+	in *bufio.Reader
+	out io.Writer
+	complete func(line string) []string
+	history []string
+}
+
+func newEditor(in io.Reader, out io.Writer) *editor {
+	return &editor{
+		in: bufio.NewReader(in),
+		out: out,
+		complete: Complete,
+		history: []string{},
+	}
+}
+
+func (e *editor) ReadLine(prompt string) (string, error) {
+	line := []rune{}
+	historyIndex := len(e.history)
+	redraw := func() {
+		// Return to the start of the line and clear it.
+		fmt.Fprintf(e.out, "\r\x1b[K%s%s", prompt, string(line))
+	}
+	io.WriteString(e.out, prompt)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			if len(line) > 0 {
+				e.history = append(e.history, string(line))
+			}
+			return string(line), nil
+		case ctrlC:
+			io.WriteString(e.out, "^C\r\n")
+			line = line[:0]
+			io.WriteString(e.out, prompt)
+		case ctrlD:
+			if len(line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+		case backspace, del:
+			if len(line) > 0 {
+				line = line[:len(line) - 1]
+				redraw()
+			}
+		case ctrlU:
+			line = line[:0]
+			redraw()
+		case tab:
+			line = []rune(e.completeLine(string(line), prompt))
+			redraw()
+		case escape:
+			// Arrow keys send escape, '[' and a letter.
+			if b, _ := e.in.ReadByte(); b != '[' {
+				continue
+			}
+			switch b, _ := e.in.ReadByte(); b {
+			case 'A':
+				if historyIndex > 0 {
+					historyIndex -= 1
+					line = []rune(e.history[historyIndex])
+				}
+			case 'B':
+				if historyIndex < len(e.history) {
+					historyIndex += 1
+					line = line[:0]
+					if historyIndex < len(e.history) {
+						line = []rune(e.history[historyIndex])
+					}
+				}
+			}
+			redraw()
+		default:
+			if r >= ' ' {
+				line = append(line, r)
+				io.WriteString(e.out, string(r))
+			}
+		}
+	}
+	return string(line), nil
+}
+
+// completeLine returns the line with its last word completed as far
+// as it can be.  If there's more than one possibility and none can be
+// chosen then they are listed.
+func (e *editor) completeLine(line string, prompt string) string {
+	candidates := e.complete(line)
+	switch len(candidates) {
+	case 0:
+		io.WriteString(e.out, "\a")
+		return line
+	case 1:
+		if strings.HasSuffix(candidates[0], string(os.PathSeparator)) {
+			return candidates[0]
+		}
+		return candidates[0] + " "
+	}
+	if prefix := commonPrefix(candidates); len(prefix) > len(line) {
+		return prefix
+	}
+	words := []string{}
+	start := strings.LastIndexAny(line, " \t") + 1
+	for _, c := range candidates {
+		words = append(words, c[start:])
+	}
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(words, "  "))
+	return line
+}
+
+
+// terminalEditor is an editor for a terminal.  The terminal is put in
+// raw mode, using stty, only while a line is being read so that other
+// output is unaffected.
+type terminalEditor struct {
+	*editor
+	tty *os.File
+}
+
+// newTerminalEditor returns an error if in isn't a terminal whose mode
+// can be changed.
+func newTerminalEditor(in *os.File, out io.Writer) (*terminalEditor, error) {
+	info, err := in.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode() & os.ModeCharDevice == 0 {
+		return nil, fmt.Errorf("%s isn't a terminal", in.Name())
+	}
+	if _, err := stty(in, "-g"); err != nil {
+		return nil, err
+	}
+	return &terminalEditor{
+		editor: newEditor(in, out),
+		tty: in,
+	}, nil
+}
+
+func (t *terminalEditor) ReadLine(prompt string) (string, error) {
+	saved, err := stty(t.tty, "-g")
+	if err != nil {
+		return "", err
+	}
+	if _, err := stty(t.tty, "raw", "-echo"); err != nil {
+		return "", err
+	}
+	defer stty(t.tty, saved)
+	return t.editor.ReadLine(prompt)
+}
+
+// stty runs the stty command on the terminal and returns its output.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
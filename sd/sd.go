@@ -0,0 +1,472 @@
+// Package sd reads and writes sequences in the style of the
+// transcripts written by the Sd square dance program.
+//
+// A transcript starts with a header line which names the level, then
+// has a numbered line for each call, such as
+//
+//	 3: heads swing thru
+//
+// Each call may be followed by a diagram of the dancers, where each
+// dancer is written as its couple number, B for a boy or G for a
+// girl, and one of ^ > V < for the way it faces.  Couple 1 starts at
+// the bottom of the diagram facing up.  Adjacent positions are six
+// columns or two lines apart.
+package sd
+
+import "bufio"
+import "fmt"
+import "io"
+import "regexp"
+import "strconv"
+import "strings"
+import "unicode"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/reasoning"
+import "squaredance/sequence"
+
+
+// Columns and lines per geometry.CoupleDistance in a diagram:
+const (
+	columnsPerUnit = 6
+	linesPerUnit = 2
+)
+
+// designators maps the words Sd uses to designate dancers to the
+// names of our Roles.
+var designators = map[string]string{
+	"heads": "OriginalHeads",
+	"sides": "OriginalSides",
+	"headliners": "CurrentHeads",
+	"sideliners": "CurrentSides",
+}
+
+// directionMarks are the marks for facing up, left, down and right in
+// a diagram, which are Directions 0, 0.25, 0.5 and 0.75.
+const directionMarks = "^<V>"
+
+
+// Step is one numbered call of a Transcript.
+type Step struct {
+	Number int
+	// Text is the call as it was written.
+	Text string
+	// Call is nil if the call isn't one we know.
+	Call *sequence.Call
+	// Dancers is where the diagram following the call shows the
+	// dancers to be, or nil if there was no diagram.
+	Dancers dancer.Dancers
+}
+
+// Transcript is what Read finds in an Sd transcript.
+type Transcript struct {
+	// Level is the level named in the header, as Sd writes it.
+	Level string
+	Steps []*Step
+	// Resolve is what follows "resolve is:", if anything.
+	Resolve string
+}
+
+// Sequence returns the Calls of the Transcript.  If any call is
+// unknown it returns an error which lists them.
+func (t *Transcript) Sequence() (sequence.Sequence, error) {
+	s := sequence.Sequence{}
+	unknown := []string{}
+	for _, step := range t.Steps {
+		if step.Call == nil {
+			unknown = append(unknown, fmt.Sprintf("%d: %s", step.Number, step.Text))
+			continue
+		}
+		s = append(s, *step.Call)
+	}
+	if len(unknown) > 0 {
+		return s, fmt.Errorf("Unknown calls: %s", strings.Join(unknown, "; "))
+	}
+	return s, nil
+}
+
+
+var callLine = regexp.MustCompile(`^\s*(\d+):\s*(.*?)\s*$`)
+var comment = regexp.MustCompile(`\{[^}]*\}`)
+var dancerToken = regexp.MustCompile(`(\d)([BGX])([\^<V>])`)
+
+// Read parses an Sd transcript.
+func Read(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	scanner := bufio.NewScanner(r)
+	var current *Step
+	diagram := []string{}
+	diagramLine := 0
+	finishDiagram := func() error {
+		if current != nil && len(diagram) > 0 {
+			dancers, err := parseDiagram(diagram)
+			if err != nil {
+				return fmt.Errorf("Diagram at line %d: %s", diagramLine, err)
+			}
+			current.Dancers = dancers
+		}
+		diagram = []string{}
+		return nil
+	}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber += 1
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case lineNumber == 1 && !callLine.MatchString(line):
+			// The header.  The level is its last word.
+			if fields := strings.Fields(trimmed); len(fields) > 0 {
+				t.Level = fields[len(fields) - 1]
+			}
+		case callLine.MatchString(line):
+			if err := finishDiagram(); err != nil {
+				return nil, err
+			}
+			m := callLine.FindStringSubmatch(line)
+			number, _ := strconv.Atoi(m[1])
+			text := strings.TrimSpace(comment.ReplaceAllString(m[2], ""))
+			current = &Step{
+				Number: number,
+				Text: text,
+				Call: ParseCall(text),
+			}
+			t.Steps = append(t.Steps, current)
+		case strings.HasPrefix(trimmed, "resolve is:"):
+			t.Resolve = strings.TrimSpace(strings.TrimPrefix(trimmed, "resolve is:"))
+		case dancerToken.MatchString(line):
+			if len(diagram) == 0 {
+				diagramLine = lineNumber
+			}
+			diagram = append(diagram, line)
+		case trimmed == "" && len(diagram) > 0:
+			// Blank lines within a diagram are rows with no
+			// dancers.
+			diagram = append(diagram, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finishDiagram(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseCall returns the Call that text, as Sd would write it,
+// describes, or nil if it isn't a call we know.  "heads swing thru"
+// is the Action SwingThru designated by OriginalHeads.
+func ParseCall(text string) *sequence.Call {
+	words := strings.Fields(strings.ToLower(text))
+	roles := []reasoning.Role{}
+	for len(words) > 0 {
+		name, ok := designators[words[0]]
+		if !ok {
+			break
+		}
+		role := reasoning.LookupRole(name)
+		if role == nil {
+			return nil
+		}
+		roles = append(roles, role)
+		words = words[1:]
+	}
+	if len(words) > 0 && words[0] == "start" {
+		// As in "heads start".
+		words = words[1:]
+	}
+	name := ""
+	for _, word := range words {
+		name += strings.ToUpper(word[:1]) + word[1:]
+	}
+	a := action.FindAction(name)
+	if a == nil {
+		return nil
+	}
+	call := sequence.NewCall(a, roles...)
+	return &call
+}
+
+// ParseAnyCall accepts a Call either as sequence.Call.String writes
+// it, as in "OriginalHeads QuarterRight", or as Sd would write it, as
+// in "heads quarter right".  If it's neither then the error is the one
+// from sequence.ParseCall.
+func ParseAnyCall(text string) (sequence.Call, error) {
+	call, err := sequence.ParseCall(text)
+	if err == nil {
+		return call, nil
+	}
+	if c := ParseCall(text); c != nil {
+		return *c, nil
+	}
+	return call, err
+}
+
+// parseDiagram returns the dancers that the lines of a diagram show,
+// centered around geometry.Origin.
+func parseDiagram(lines []string) (dancer.Dancers, error) {
+	dancers := dancer.Dancers{}
+	byOrdinal := map[int]dancer.Dancer{}
+	for row, line := range lines {
+		for _, loc := range dancerToken.FindAllStringSubmatchIndex(line, -1) {
+			couple, _ := strconv.Atoi(line[loc[2]:loc[3]])
+			gender := dancer.Unspecified
+			ordinal := -1
+			switch line[loc[4]:loc[5]] {
+			case "B":
+				gender = dancer.Guy
+				ordinal = 2 * (couple - 1)
+			case "G":
+				gender = dancer.Gal
+				ordinal = 2 * (couple - 1) + 1
+			}
+			if ordinal < 0 {
+				ordinal = 1000 + len(dancers)
+			}
+			if _, ok := byOrdinal[ordinal]; ok {
+				return nil, fmt.Errorf("%s appears twice", line[loc[0]:loc[1]])
+			}
+			quarter := strings.Index(directionMarks, line[loc[6]:loc[7]])
+			d := dancer.NewDancer(ordinal, couple, gender)
+			// Up the diagram is Down and left is Left.
+			d.Move(geometry.NewPositionDownLeft(
+				geometry.Down(-float32(row) / linesPerUnit),
+				geometry.Left(-float32(loc[0]) / columnsPerUnit)),
+				geometry.Direction(float32(quarter) / 4))
+			byOrdinal[ordinal] = d
+			dancers = append(dancers, d)
+		}
+	}
+	for _, d := range dancers {
+		if d.Gender() == dancer.Guy {
+			if partner, ok := byOrdinal[d.Ordinal() + 1]; ok {
+				d.SetOriginalPartner(partner)
+				partner.SetOriginalPartner(d)
+			}
+		}
+	}
+	return dancers.Recenter0().Ordered(), nil
+}
+
+
+// Write runs the Sequence from a squared set of four couples and
+// writes a transcript of it, with a diagram after each call, in the
+// style of Sd.  If any diagram is inexact then an ErrInexact is
+// returned once the transcript has been written.
+func Write(w io.Writer, level action.Level, s sequence.Sequence) error {
+	set := dancer.NewSquaredSet(4)
+	bw := bufio.NewWriter(w)
+	var inexact error
+	fmt.Fprintf(bw, "squaredance     %s\n\n", SdLevel(level))
+	for i, call := range s {
+		if err := (sequence.Sequence{ call }).Apply(set.Dancers()); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "%2d: %s\n\n", i + 1, FormatCall(call))
+		if shared := writeDiagram(bw, set.Dancers()); len(shared) > 0 && inexact == nil {
+			inexact = &ErrInexact{
+				Number: i + 1,
+				Shared: shared,
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	if r := set.Resolve(); r.Resolved() {
+		resolve := "at home"
+		if len(r.Calls) > 0 {
+			resolve = strings.ToLower(strings.Join(r.Calls, ", "))
+		}
+		fmt.Fprintf(bw, "resolve is: %s\n", resolve)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return inexact
+}
+
+
+// ErrInexact is returned by Write if a diagram can't show every dancer
+// because more than one of them is nearest to the same place.  The
+// whole transcript is still written.
+type ErrInexact struct {
+	// Number is the number of the call which the first such
+	// diagram follows.
+	Number int
+	// Shared are the dancers of that diagram which are nearest to
+	// the same place as some other dancer.  Those places are marked
+	// with a "*".
+	Shared dancer.Dancers
+}
+
+func (e *ErrInexact) Error() string {
+	names := []string{}
+	for _, d := range e.Shared {
+		names = append(names, d.String())
+	}
+	return fmt.Sprintf("Diagram after call %d is inexact, sharing places: %s",
+		e.Number, strings.Join(names, ", "))
+}
+
+// SdLevel returns the name Sd uses for the Level.
+func SdLevel(level action.Level) string {
+	return strings.Replace(level.String(), "_", "", -1)
+}
+
+// FormatCall returns the Call as Sd would write it.
+func FormatCall(call sequence.Call) string {
+	parts := []string{}
+	for _, role := range call.Designators {
+		for word, name := range designators {
+			if name == role.Name() {
+				parts = append(parts, word)
+			}
+		}
+	}
+	parts = append(parts, strings.ToLower(strings.Join(words([]string{ call.Action.Name() }), " ")))
+	return strings.Join(parts, " ")
+}
+
+// words splits each CamelCase name into space separated words.
+func words(names []string) []string {
+	result := []string{}
+	for _, name := range names {
+		var b strings.Builder
+		for i, r := range name {
+			if i > 0 && unicode.IsUpper(r) {
+				b.WriteRune(' ')
+			}
+			b.WriteRune(r)
+		}
+		result = append(result, b.String())
+	}
+	return result
+}
+
+// sharedMark is written in place of dancers which are nearest to the
+// same place in a diagram.
+const sharedMark = "*"
+
+// writeDiagram writes a diagram of the dancers.  It returns those
+// dancers which couldn't be shown because another dancer is nearest
+// to the same place.
+func writeDiagram(w io.Writer, dancers dancer.Dancers) dancer.Dancers {
+	// The top left corner of the diagram is the most Down and most
+	// Left position.
+	top, left := dancers[0].Position().Down, dancers[0].Position().Left
+	for _, d := range dancers {
+		if d.Position().Down > top {
+			top = d.Position().Down
+		}
+		if d.Position().Left > left {
+			left = d.Position().Left
+		}
+	}
+	type place struct {
+		row, column int
+	}
+	places := map[dancer.Dancer]place{}
+	count := map[place]int{}
+	for _, d := range dancers {
+		p := place{
+			row: round(float32(top - d.Position().Down) * linesPerUnit),
+			column: round(float32(left - d.Position().Left) * columnsPerUnit),
+		}
+		places[d] = p
+		count[p] += 1
+	}
+	shared := dancer.Dancers{}
+	rows := map[int]map[int]string{}
+	maxRow := 0
+	for _, d := range dancers {
+		p := places[d]
+		if rows[p.row] == nil {
+			rows[p.row] = map[int]string{}
+		}
+		if count[p] > 1 {
+			shared = append(shared, d)
+			rows[p.row][p.column] = sharedMark
+		} else {
+			rows[p.row][p.column] = formatDancer(d)
+		}
+		if p.row > maxRow {
+			maxRow = p.row
+		}
+	}
+	for row := 0; row <= maxRow; row++ {
+		line := []byte{}
+		for column, token := range rows[row] {
+			for len(line) < column + len(token) {
+				line = append(line, ' ')
+			}
+			copy(line[column:], token)
+		}
+		if len(line) == 0 {
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	return shared
+}
+
+func round(f float32) int {
+	if f < 0 {
+		return -round(-f)
+	}
+	return int(f + 0.5)
+}
+
+func formatDancer(d dancer.Dancer) string {
+	gender := "X"
+	switch d.Gender() {
+	case dancer.Guy:
+		gender = "B"
+	case dancer.Gal:
+		gender = "G"
+	}
+	quarter := round(float32(d.Direction()) * 4)
+	quarter = (quarter % 4 + 4) % 4
+	return fmt.Sprintf("%d%s%c", d.CoupleNumber(), gender, directionMarks[quarter])
+}
+
+
+// Discrepancy is a difference between where a Transcript says the
+// dancers are after a Step and where we put them.
+type Discrepancy struct {
+	Step *Step
+	Reason string
+}
+
+func (d *Discrepancy) String() string {
+	return fmt.Sprintf("%d: %s: %s", d.Step.Number, d.Step.Text, d.Reason)
+}
+
+// CrossCheck dances the Transcript from a squared set of four couples
+// and compares the dancers with each of its diagrams, up to rotation
+// of the whole set.  It stops at the first call that is unknown or
+// that can't be done.
+func CrossCheck(t *Transcript) []*Discrepancy {
+	set := dancer.NewSquaredSet(4)
+	discrepancies := []*Discrepancy{}
+	for _, step := range t.Steps {
+		if step.Call == nil {
+			return append(discrepancies, &Discrepancy{ step, "unknown call" })
+		}
+		if err := (sequence.Sequence{ *step.Call }).Apply(set.Dancers()); err != nil {
+			return append(discrepancies, &Discrepancy{ step, err.Error() })
+		}
+		if step.Dancers == nil {
+			continue
+		}
+		ours := set.Dancers().Copy().Recenter0()
+		if ours.CanonicalFingerprint(geometry.Origin) !=
+			step.Dancers.CanonicalFingerprint(geometry.Origin) {
+			discrepancies = append(discrepancies, &Discrepancy{
+				step, "the dancers are in different places",
+			})
+		}
+	}
+	return discrepancies
+}
@@ -0,0 +1,140 @@
+package sd
+
+import "bytes"
+import "strings"
+import "testing"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/reasoning"
+import "squaredance/sequence"
+
+
+const quarterLeft = `Sd38.89:db38.89     Mainstream
+     Sun Jan  1 12:00:00 2017
+
+ 1: quarter left {everyone}
+
+          3G>   3B>
+
+    4B^               2GV
+
+    4G^               2BV
+
+          1B<   1G<
+
+ 2: heads star thru
+`
+
+func TestRead(t *testing.T) {
+	transcript, err := Read(strings.NewReader(quarterLeft))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if want, got := "Mainstream", transcript.Level; got != want {
+		t.Errorf("Wrong level: want %q, got %q", want, got)
+	}
+	if want, got := 2, len(transcript.Steps); got != want {
+		t.Fatalf("Wrong number of steps: want %d, got %d", want, got)
+	}
+	step := transcript.Steps[0]
+	if step.Text != "quarter left" || step.Call == nil ||
+		step.Call.Action.Name() != "QuarterLeft" {
+		t.Errorf("Wrong first step: %q %v", step.Text, step.Call)
+	}
+	if want, got := 8, len(step.Dancers); got != want {
+		t.Errorf("Wrong number of dancers: want %d, got %d", want, got)
+	}
+	if transcript.Steps[1].Call != nil {
+		t.Errorf("star thru shouldn't be known")
+	}
+	if _, err := transcript.Sequence(); err == nil {
+		t.Errorf("Sequence should report the unknown call")
+	}
+	discrepancies := CrossCheck(transcript)
+	if len(discrepancies) != 1 || discrepancies[0].Step.Number != 2 {
+		t.Errorf("Expected only the unknown call to be a discrepancy: %v", discrepancies)
+	}
+}
+
+func TestParseCall(t *testing.T) {
+	call := ParseCall("heads start quarter right")
+	if call == nil {
+		t.Fatalf("Call not recognized")
+	}
+	if want, got := "OriginalHeads QuarterRight", call.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if want, got := "heads quarter right", FormatCall(*call); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestParseAnyCall(t *testing.T) {
+	for _, text := range []string{ "OriginalHeads QuarterRight", "heads quarter right" } {
+		call, err := ParseAnyCall(text)
+		if err != nil {
+			t.Errorf("%q: %s", text, err)
+			continue
+		}
+		if want, got := "OriginalHeads QuarterRight", call.String(); got != want {
+			t.Errorf("%q: want %q, got %q", text, want, got)
+		}
+	}
+	if _, err := ParseAnyCall("Frobnicate"); err == nil {
+		t.Errorf("Expected an error for an unknown call")
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	heads := reasoning.LookupRole("OriginalHeads")
+	s := sequence.Sequence{
+		sequence.NewCall(action.FindAction("Meet"), heads),
+		sequence.NewCall(action.FindAction("QuarterLeft"), heads),
+	}
+	buf := bytes.NewBufferString("")
+	if err := Write(buf, action.Mainstream, s); err != nil {
+		t.Fatalf("Write: %s", err)
+	}
+	transcript, err := Read(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Read: %s\n%s", err, buf)
+	}
+	s2, err := transcript.Sequence()
+	if err != nil {
+		t.Fatalf("Sequence: %s\n%s", err, buf)
+	}
+	if len(s2) != len(s) || s2[0].String() != s[0].String() || s2[1].String() != s[1].String() {
+		t.Errorf("want %v, got %v", s, s2)
+	}
+	for _, d := range CrossCheck(transcript) {
+		t.Errorf("%s\n%s", d, buf)
+	}
+	// Garble a diagram:
+	garbled := strings.Replace(buf.String(), "1B", "1Q", 1)
+	garbled = strings.Replace(garbled, "2G", "1B", 1)
+	garbled = strings.Replace(garbled, "1Q", "2G", 1)
+	transcript, err = Read(strings.NewReader(garbled))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if len(CrossCheck(transcript)) == 0 {
+		t.Errorf("Swapping dancers wasn't noticed:\n%s", garbled)
+	}
+}
+
+func TestWriteDiagramShared(t *testing.T) {
+	dancers := dancer.NewSquaredSet(4).Dancers()
+	dancers[1].Move(dancers[0].Position(), dancers[0].Direction())
+	buf := bytes.NewBufferString("")
+	shared := writeDiagram(buf, dancers)
+	if want, got := 2, len(shared); got != want {
+		t.Fatalf("Wrong number of shared dancers: want %d, got %d: %v\n%s", want, got, shared, buf)
+	}
+	if !strings.Contains(buf.String(), sharedMark) {
+		t.Errorf("Shared place isn't marked:\n%s", buf)
+	}
+	if strings.Contains(buf.String(), formatDancer(dancers[0])) ||
+		strings.Contains(buf.String(), formatDancer(dancers[1])) {
+		t.Errorf("Shared dancers are still shown:\n%s", buf)
+	}
+}
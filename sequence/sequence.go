@@ -0,0 +1,226 @@
+// Package sequence runs square dance choreography: a list of calls
+// that are danced one after another, starting from a squared set.
+package sequence
+
+import "bytes"
+import "fmt"
+import "strings"
+import "time"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/reasoning"
+import "squaredance/timeline"
+
+
+// Call is one step of a Sequence: an Action and the Roles that
+// designate which dancers do it.  If there are no designators then
+// everyone does the Action.
+type Call struct {
+	Action action.Action
+	Designators []reasoning.Role
+}
+
+// NewCall returns a Call of the Action for the designated dancers.
+func NewCall(a action.Action, designators ...reasoning.Role) Call {
+	return Call{
+		Action: a,
+		Designators: designators,
+	}
+}
+
+func (c Call) String() string {
+	buf := bytes.NewBufferString("")
+	for _, role := range c.Designators {
+		fmt.Fprintf(buf, "%s ", role.Name())
+	}
+	buf.WriteString(c.Action.Name())
+	return buf.String()
+}
+
+// ParseCall returns the Call written as Call.String writes it: the
+// names of any designating Roles followed by the name of the Action,
+// separated by spaces, as in "OriginalHeads QuarterLeft".
+func ParseCall(text string) (Call, error) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return Call{}, fmt.Errorf("No call in %q", text)
+	}
+	a := action.FindAction(words[len(words) - 1])
+	if a == nil {
+		return Call{}, fmt.Errorf("Unknown action %q", words[len(words) - 1])
+	}
+	roles := []reasoning.Role{}
+	for _, name := range words[:len(words) - 1] {
+		role := reasoning.LookupRole(name)
+		if role == nil {
+			return Call{}, fmt.Errorf("Unknown role %q", name)
+		}
+		roles = append(roles, role)
+	}
+	return NewCall(a, roles...), nil
+}
+
+
+// Sequence is a list of Calls.
+type Sequence []Call
+
+// Beats returns how long it takes to dance the Sequence.
+func (s Sequence) Beats() int {
+	beats := 0
+	for _, c := range s {
+		beats += c.Action.Beats()
+	}
+	return beats
+}
+
+
+// Step records what happened when one Call of a Sequence was danced.
+type Step struct {
+	Call Call
+	Result *action.Result
+	// Time is when the Call finished, in beats from the start of
+	// the Sequence.
+	Time timeline.Time
+	// Formations are the Formations that the dancers were in
+	// after the Call.
+	Formations []reasoning.Formation
+}
+
+
+// Run records the dancing of a Sequence.
+type Run struct {
+	Set dancer.Set
+	// Timeline has a DancerSnapshot for every beat of the Run.
+	Timeline timeline.Timeline
+	// Start are the Formations that the dancers were in before
+	// the first Call.
+	Start []reasoning.Formation
+	Steps []Step
+}
+
+// Beats returns how long the Run took, including any time the
+// dancers spent breathing.
+func (r *Run) Beats() int {
+	return int(r.Timeline.MostRecent())
+}
+
+// Duration returns how long the Run takes at the specified Tempo.
+func (r *Run) Duration(tempo action.Tempo) time.Duration {
+	return tempo.Duration(r.Beats())
+}
+
+// Formations returns the Formations that the dancers are in at the
+// end of the Run.
+func (r *Run) Formations() []reasoning.Formation {
+	if len(r.Steps) == 0 {
+		return r.Start
+	}
+	return r.Steps[len(r.Steps) - 1].Formations
+}
+
+
+// ErrIllegalCall is returned by Sequence.Run when a Call can't be
+// done from the formation the dancers are in.
+type ErrIllegalCall struct {
+	// Index is the position of the Call in the Sequence.
+	Index int
+	Call Call
+	Formations []reasoning.Formation
+	// Err is why the Call couldn't be done.  It is nil if the Call
+	// could only be done by some of the designated dancers.
+	Err error
+	// LeftOut are the designated dancers who couldn't do the Call.
+	LeftOut dancer.Dancers
+}
+
+func (e *ErrIllegalCall) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("Call %d, %s, isn't legal from %v: %s",
+			e.Index + 1, e.Call, e.Formations, e.Err)
+	}
+	return fmt.Sprintf("Call %d, %s, isn't legal from %v: %s can't do it",
+		e.Index + 1, e.Call, e.Formations, e.LeftOut)
+}
+
+func (e *ErrIllegalCall) Unwrap() error {
+	return e.Err
+}
+
+
+// BreathingBeats is how long the dancers take to breathe apart after
+// a Call that left them too close together.
+const BreathingBeats = 1
+
+// Run dances the Sequence with the dancers of the Set, which is
+// normally a new squared set from dancer.NewSquaredSet.  Dancers that
+// get too close together breathe apart after each Call, which takes
+// another BreathingBeats.  It stops at the first Call that can't be
+// done, returning an ErrIllegalCall along with the Run so far.
+func (s Sequence) Run(set dancer.Set) (*Run, error) {
+	run := &Run{
+		Set: set,
+		Timeline: timeline.NewTimeline(set.Dancers()),
+		Start: reasoning.Recognize(set.Dancers()),
+		Steps: []Step{},
+	}
+	run.Timeline.MakeSnapshot(0)
+	now := timeline.Time(0)
+	for i, call := range s {
+		result, err := doCall(set.Dancers(), i, call)
+		if err != nil {
+			return run, err
+		}
+		now = action.RecordResult(run.Timeline, now, result, 1)
+		if b := result.Validation.Breathing; b != nil && len(b.Moved) > 0 {
+			// The Paths end where the dancers were before they
+			// breathed apart.
+			now += BreathingBeats
+			run.Timeline.MakeSnapshot(now)
+		}
+		run.Steps = append(run.Steps, Step{
+			Call: call,
+			Result: result,
+			Time: now,
+			Formations: reasoning.Recognize(set.Dancers()),
+		})
+	}
+	return run, nil
+}
+
+// Apply dances the Sequence with the dancers, like Run but without
+// recording anything.  It is useful for trying out choreography on
+// copies of the dancers of a Set.
+func (s Sequence) Apply(dancers dancer.Dancers) error {
+	for i, call := range s {
+		if _, err := doCall(dancers, i, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// doCall has the dancers do the Call, which is at index in its
+// Sequence.  If not all of the designated dancers can do the Call then
+// nobody moves and an ErrIllegalCall is returned.
+func doCall(dancers dancer.Dancers, index int, call Call) (*action.Result, error) {
+	before := dancers.Copy()
+	result, err := action.PerformChecked(dancers, call.Action,
+		action.BreatheOnCollision, call.Designators...)
+	if err == nil && result.Complete() {
+		return result, nil
+	}
+	// Undo the part of the Call that could be done:
+	for i, d := range dancers {
+		d.Move(before[i].Position(), before[i].Direction())
+	}
+	illegal := &ErrIllegalCall{
+		Index: index,
+		Call: call,
+		Formations: reasoning.Recognize(dancers),
+		Err: err,
+	}
+	if result != nil {
+		illegal.LeftOut = result.LeftOut
+	}
+	return nil, illegal
+}
@@ -0,0 +1,99 @@
+package sequence
+
+import "errors"
+import "testing"
+import "squaredance/action"
+import "squaredance/dancer"
+import "squaredance/notation"
+import "squaredance/reasoning"
+
+
+func TestRun(t *testing.T) {
+	heads := reasoning.LookupRole("OriginalHeads")
+	seq := Sequence{
+		NewCall(action.FindAction("Meet"), heads),
+		NewCall(action.FindAction("QuarterLeft"), heads),
+	}
+	run, err := seq.Run(dancer.NewSquaredSet(4))
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if want, got := 2, len(run.Steps); got != want {
+		t.Fatalf("Wrong number of steps: want %d, got %d", want, got)
+	}
+	if want, got := seq.Beats(), run.Beats(); got != want {
+		t.Errorf("Wrong number of beats: want %d, got %d", want, got)
+	}
+	if want, got := run.Steps[1].Time, run.Timeline.MostRecent(); got != want {
+		t.Errorf("Wrong final time: want %d, got %d", want, got)
+	}
+	if len(run.Formations()) == 0 {
+		t.Errorf("No formations recognized")
+	}
+}
+
+func TestRunBreathing(t *testing.T) {
+	dancers, err := notation.Parse("> < .\n> . <\n")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	set := dancer.NewSet(dancers)
+	seq := Sequence{ NewCall(action.FindAction("ForwardLeft")) }
+	run, err := seq.Run(set)
+	if err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if b := run.Steps[0].Result.Validation.Breathing; b == nil || len(b.Moved) == 0 {
+		t.Fatalf("Nobody breathed")
+	}
+	if want, got := seq.Beats() + BreathingBeats, run.Beats(); got != want {
+		t.Errorf("Wrong number of beats: want %d, got %d", want, got)
+	}
+	for _, d := range set.Dancers() {
+		s := run.Timeline.FindSnapshot(d, run.Timeline.MostRecent())
+		if s == nil {
+			t.Fatalf("No final snapshot of %s", d)
+		}
+		if !s.Position().Equal(d.Position()) || !s.Direction().Equal(d.Direction()) {
+			t.Errorf("The final snapshot of %s is at %s %v, not %s %v", d,
+				s.Position(), s.Direction(), d.Position(), d.Direction())
+		}
+	}
+}
+
+func TestRunIllegal(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	seq := Sequence{
+		NewCall(action.FindAction("Meet"), reasoning.LookupRole("OriginalHeads")),
+		// Nobody is in a MiniWave:
+		NewCall(action.FindAction("PassToBacks")),
+	}
+	run, err := seq.Run(set)
+	var illegal *ErrIllegalCall
+	if !errors.As(err, &illegal) {
+		t.Fatalf("Expected ErrIllegalCall, got %v", err)
+	}
+	if want, got := 1, illegal.Index; got != want {
+		t.Errorf("Wrong index: want %d, got %d", want, got)
+	}
+	if want, got := 1, len(run.Steps); got != want {
+		t.Errorf("Wrong number of steps: want %d, got %d", want, got)
+	}
+	t.Logf("%s", err)
+}
+
+func TestParseCall(t *testing.T) {
+	call := NewCall(action.FindAction("QuarterLeft"), reasoning.LookupRole("OriginalHeads"))
+	parsed, err := ParseCall(call.String())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := call.String(), parsed.String(); got != want {
+		t.Errorf("Wrong call: want %q, got %q", want, got)
+	}
+	for _, text := range []string{ "", "OriginalHeads Frobnicate", "Nobody QuarterLeft" } {
+		if _, err := ParseCall(text); err == nil {
+			t.Errorf("Expected an error parsing %q", text)
+		}
+	}
+}
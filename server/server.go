@@ -0,0 +1,494 @@
+// Package server makes the dancing and reasoning engine available
+// over HTTP, using JSON, for use by choreography editors.
+//
+// A client creates a session, which holds a set of dancers and the
+// calls they have done, and then works with it by its ID:
+//
+//	POST   /sessions                     create a session
+//	GET    /sessions/{id}                the session's state
+//	DELETE /sessions/{id}                forget the session
+//	POST   /sessions/{id}/calls          do more calls
+//	POST   /sessions/{id}/undo           take back the last call
+//	GET    /sessions/{id}/formations     the formations the dancers are in
+//	GET    /sessions/{id}/roles          the dancers in each role
+//	GET    /sessions/{id}/timeline       the Timeline of the calls as JSON
+//	GET    /sessions/{id}/timeline.svg   the Timeline as an animated SVG image
+//	GET    /sessions/{id}/timeline.html  the Timeline as an HTML page with controls
+//
+// The body of a request to create a session is empty, for a squared set
+// of four couples, or describes the starting dancers as a diagram in
+// the notation of package notation, as the JSON of a dancer.Set or as
+// the JSON of dancer.Dancers.  The body of a request to do calls is a
+// CallsJSON.  Calls are written as sequence.Call.String writes them or
+// as Sd would.  Errors are reported as an ErrorJSON.
+//
+// Sessions that haven't been used for SessionIdleTimeout are
+// forgotten, as is the least recently used session when creating
+// another would make more than MaxSessions.
+package server
+
+import "bytes"
+import "crypto/rand"
+import "encoding/hex"
+import "encoding/json"
+import "errors"
+import "fmt"
+import "io"
+import "net/http"
+import "strings"
+import "sync"
+import "time"
+import "squaredance/dancer"
+import "squaredance/notation"
+import "squaredance/reasoning"
+import "squaredance/sd"
+import "squaredance/sequence"
+import "squaredance/svg"
+
+
+// MaxRequestSize limits the size of the body of a request.
+const MaxRequestSize = 1 << 20
+
+// SessionIdleTimeout is how long a session is kept after the last
+// request for it.
+const SessionIdleTimeout = time.Hour
+
+// MaxSessions limits how many sessions a Server keeps.
+const MaxSessions = 1000
+
+// engine serializes use of the reasoning and action packages.
+// reasoning.FindFormations shares one FormationFinder among all of its
+// callers, so only one goroutine at a time can recognize formations or
+// perform Actions.
+var engine sync.Mutex
+
+
+// CallsJSON is the body of a request to do calls.
+type CallsJSON struct {
+	Calls []string               `json:"calls"`
+}
+
+// ErrorJSON is the body of a response to a request that failed.
+type ErrorJSON struct {
+	Error string                 `json:"error"`
+	// Index is the index, among all of the session's calls, of a
+	// call that couldn't be done.
+	Index *int                   `json:"index,omitempty"`
+}
+
+// ResolveJSON describes how close the dancers are to being resolved.
+type ResolveJSON struct {
+	Resolved bool                `json:"resolved"`
+	Point string                 `json:"point"`
+	Paired int                   `json:"paired"`
+	InSequence bool              `json:"inSequence"`
+	// Calls is advice for the caller, as dancer.Resolution.Calls
+	// describes.  Unlike SessionJSON.Calls they can't necessarily
+	// be sent back as calls to do.
+	Calls []string               `json:"calls"`
+}
+
+// SessionJSON is the state of a session.
+type SessionJSON struct {
+	ID string                    `json:"id"`
+	Calls []string               `json:"calls"`
+	Beats int                    `json:"beats"`
+	Set *dancer.SetImpl          `json:"set"`
+	Diagram string               `json:"diagram"`
+	// DiagramError says why Diagram doesn't show exactly where the
+	// dancers are, if it doesn't.
+	DiagramError string          `json:"diagramError,omitempty"`
+	Formations []reasoning.FormationJSON `json:"formations"`
+	Resolve ResolveJSON          `json:"resolve"`
+}
+
+// RoleJSON lists the dancers, by Ordinal, who are in a Role.
+type RoleJSON struct {
+	Role string                  `json:"role"`
+	Dancers []int                `json:"dancers"`
+}
+
+// RolesJSON is the response to a roles request.
+type RolesJSON struct {
+	Version int                  `json:"version"`
+	Roles []RoleJSON             `json:"roles"`
+}
+
+
+// session is a set of dancers and the calls they've done.  The dancers
+// of run are those of a copy of start which have done calls.
+type session struct {
+	mu sync.Mutex
+	id string
+	// lastUsed is when the session was last looked up.  It is
+	// guarded by the Server's mu rather than the session's.
+	lastUsed time.Time
+	start dancer.Dancers
+	calls sequence.Sequence
+	run *sequence.Run
+}
+
+func newSession(id string, start dancer.Dancers) (*session, error) {
+	s := &session{
+		id: id,
+		start: start,
+		calls: sequence.Sequence{},
+	}
+	if err := s.dance(s.calls); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dance has a copy of the starting dancers do the calls.  If they
+// can't then the session is unchanged.  s.mu must be held.
+func (s *session) dance(calls sequence.Sequence) error {
+	engine.Lock()
+	defer engine.Unlock()
+	run, err := calls.Run(dancer.NewSet(s.start))
+	if err != nil {
+		return err
+	}
+	s.calls = calls
+	s.run = run
+	return nil
+}
+
+// state returns the SessionJSON of the session.  s.mu must be held.
+func (s *session) state() (*SessionJSON, error) {
+	sj := &SessionJSON{
+		ID: s.id,
+		Calls: callStrings(s.calls),
+		Beats: s.run.Beats(),
+		Set: s.run.Set.(*dancer.SetImpl),
+		Formations: []reasoning.FormationJSON{},
+	}
+	var err error
+	if sj.Diagram, err = notation.Format(s.run.Set.Dancers()); err != nil {
+		sj.DiagramError = err.Error()
+	}
+	engine.Lock()
+	defer engine.Unlock()
+	for _, f := range s.run.Formations() {
+		fj, err := reasoning.NewFormationJSON(f)
+		if err != nil {
+			return nil, err
+		}
+		sj.Formations = append(sj.Formations, fj)
+	}
+	r := s.run.Set.Resolve()
+	sj.Resolve = ResolveJSON{
+		Resolved: r.Resolved(),
+		Point: r.Point.String(),
+		Paired: r.Paired,
+		InSequence: r.InSequence,
+		Calls: append([]string{}, r.Calls...),
+	}
+	return sj, nil
+}
+
+// roles returns, for each Role that some dancer is in, which dancers
+// are in it.  Roles like OriginalHeads apply to the whole set and
+// others to the recognized Formations.  s.mu must be held.
+func (s *session) roles(name string) *RolesJSON {
+	formations := append([]reasoning.Formation{ s.run.Set }, s.run.Formations()...)
+	rj := &RolesJSON{
+		Version: dancer.JSONVersion,
+		Roles: []RoleJSON{},
+	}
+	for _, role := range reasoning.Roles {
+		if name != "" && role.Name() != name {
+			continue
+		}
+		in := map[dancer.Dancer]bool{}
+		for _, f := range formations {
+			if !role.MeaningfulTo(f) {
+				continue
+			}
+			for _, d := range role.Dancers(f) {
+				in[d] = true
+			}
+		}
+		if len(in) == 0 {
+			continue
+		}
+		j := RoleJSON{ Role: role.Name(), Dancers: []int{} }
+		for _, d := range s.run.Set.Dancers() {
+			if in[d] {
+				j.Dancers = append(j.Dancers, d.Ordinal())
+			}
+		}
+		rj.Roles = append(rj.Roles, j)
+	}
+	return rj
+}
+
+
+// Server is an http.Handler which serves the API described in the
+// package documentation.  It is safe for concurrent use.
+type Server struct {
+	mu sync.Mutex
+	sessions map[string]*session
+	idleTimeout time.Duration
+	maxSessions int
+	// now returns the current time.  Tests replace it.
+	now func() time.Time
+}
+
+// NewServer returns a Server with no sessions.
+func NewServer() *Server {
+	return &Server{
+		sessions: map[string]*session{},
+		idleTimeout: SessionIdleTimeout,
+		maxSessions: MaxSessions,
+		now: time.Now,
+	}
+}
+
+// statusError is an error and the HTTP status to report it with.
+type statusError struct {
+	status int
+	err error
+	index *int
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func errorf(status int, format string, args ...interface{}) error {
+	return &statusError{ status: status, err: fmt.Errorf(format, args...) }
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestSize)
+	if err := srv.route(w, r); err != nil {
+		status, ej := http.StatusInternalServerError, ErrorJSON{ Error: err.Error() }
+		var se *statusError
+		if errors.As(err, &se) {
+			status, ej.Index = se.status, se.index
+		}
+		writeJSON(w, status, ej)
+	}
+}
+
+func (srv *Server) route(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "sessions" || len(parts) > 3 {
+		return errorf(http.StatusNotFound, "No such resource %s", r.URL.Path)
+	}
+	if len(parts) == 1 {
+		if r.Method != http.MethodPost {
+			return errorf(http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+		}
+		return srv.create(w, r)
+	}
+	s := srv.lookup(parts[1])
+	if s == nil {
+		return errorf(http.StatusNotFound, "No session %q", parts[1])
+	}
+	resource := ""
+	if len(parts) == 3 {
+		resource = parts[2]
+	}
+	method := methodFor(resource)
+	if method == "" {
+		return errorf(http.StatusNotFound, "No such resource %s", r.URL.Path)
+	}
+	if r.Method != method && !(resource == "" && r.Method == http.MethodDelete) {
+		return errorf(http.StatusMethodNotAllowed, "%s not allowed", r.Method)
+	}
+	if r.Method == http.MethodDelete {
+		srv.mu.Lock()
+		delete(srv.sessions, s.id)
+		srv.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch resource {
+	case "calls":
+		return doCalls(w, r, s)
+	case "undo":
+		if len(s.calls) == 0 {
+			return errorf(http.StatusConflict, "Nothing to undo")
+		}
+		if err := s.dance(s.calls[:len(s.calls) - 1]); err != nil {
+			return err
+		}
+	case "formations":
+		engine.Lock()
+		formations, err := reasoning.MarshalFormations(s.run.Formations())
+		engine.Unlock()
+		if err != nil {
+			return err
+		}
+		return writeRaw(w, "application/json", formations)
+	case "roles":
+		return writeJSON(w, http.StatusOK, s.roles(r.URL.Query().Get("role")))
+	case "timeline":
+		return writeJSON(w, http.StatusOK, s.run.Timeline)
+	case "timeline.svg":
+		return writeRaw(w, "image/svg+xml",
+			[]byte(svg.Animate(s.run.Timeline, svg.AnimationOptions{})))
+	case "timeline.html":
+		buf := bytes.NewBufferString("")
+		if err := svg.WriteAnimationPage(buf, strings.Join(callStrings(s.calls), ", "),
+			s.run.Timeline, svg.AnimationOptions{}); err != nil {
+			return err
+		}
+		return writeRaw(w, "text/html; charset=utf-8", buf.Bytes())
+	}
+	return writeState(w, http.StatusOK, s)
+}
+
+// methodFor returns the HTTP method for a resource of a session, or
+// "" if there's no such resource.
+func methodFor(resource string) string {
+	switch resource {
+	case "calls", "undo":
+		return http.MethodPost
+	case "", "formations", "roles", "timeline", "timeline.svg", "timeline.html":
+		return http.MethodGet
+	}
+	return ""
+}
+
+// lookup returns the session with the id, or nil if there is none or
+// it has been idle for too long.
+func (srv *Server) lookup(id string) *session {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	now := srv.now()
+	s := srv.sessions[id]
+	if s == nil {
+		return nil
+	}
+	if now.Sub(s.lastUsed) > srv.idleTimeout {
+		delete(srv.sessions, id)
+		return nil
+	}
+	s.lastUsed = now
+	return s
+}
+
+// add adds the session, first forgetting any that have been idle for
+// too long and then, if there are still too many, the least recently
+// used.
+func (srv *Server) add(s *session) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	now := srv.now()
+	for id, other := range srv.sessions {
+		if now.Sub(other.lastUsed) > srv.idleTimeout {
+			delete(srv.sessions, id)
+		}
+	}
+	for len(srv.sessions) >= srv.maxSessions && len(srv.sessions) > 0 {
+		var oldest *session
+		for _, other := range srv.sessions {
+			if oldest == nil || other.lastUsed.Before(oldest.lastUsed) {
+				oldest = other
+			}
+		}
+		delete(srv.sessions, oldest.id)
+	}
+	s.lastUsed = now
+	srv.sessions[s.id] = s
+}
+
+func (srv *Server) create(w http.ResponseWriter, r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errorf(http.StatusBadRequest, "%s", err)
+	}
+	start := dancer.NewSquaredSet(4).Dancers()
+	if len(bytes.TrimSpace(body)) > 0 {
+		if start, err = notation.ParseDancers(body); err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	s, err := newSession(id, start)
+	if err != nil {
+		return err
+	}
+	srv.add(s)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Location", "/sessions/" + id)
+	return writeState(w, http.StatusCreated, s)
+}
+
+func doCalls(w http.ResponseWriter, r *http.Request, s *session) error {
+	cj := CallsJSON{}
+	if err := json.NewDecoder(r.Body).Decode(&cj); err != nil {
+		return errorf(http.StatusBadRequest, "%s", err)
+	}
+	calls := append(sequence.Sequence{}, s.calls...)
+	for _, text := range cj.Calls {
+		call, err := sd.ParseAnyCall(text)
+		if err != nil {
+			return errorf(http.StatusBadRequest, "%s", err)
+		}
+		calls = append(calls, call)
+	}
+	if err := s.dance(calls); err != nil {
+		var illegal *sequence.ErrIllegalCall
+		if errors.As(err, &illegal) {
+			return &statusError{
+				status: http.StatusUnprocessableEntity,
+				err: err,
+				index: &illegal.Index,
+			}
+		}
+		return err
+	}
+	return writeState(w, http.StatusOK, s)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func callStrings(calls sequence.Sequence) []string {
+	result := []string{}
+	for _, call := range calls {
+		result = append(result, call.String())
+	}
+	return result
+}
+
+// writeState writes the state of the session.  s.mu must be held.
+func writeState(w http.ResponseWriter, status int, s *session) error {
+	sj, err := s.state()
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, status, sj)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func writeRaw(w http.ResponseWriter, contentType string, data []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,222 @@
+package server
+
+import "encoding/json"
+import "fmt"
+import "io"
+import "net/http"
+import "net/http/httptest"
+import "strings"
+import "sync"
+import "time"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/notation"
+import "squaredance/timeline"
+
+
+func request(t *testing.T, srv http.Handler, method, path, body string, wantStatus int) []byte {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+	data, _ := io.ReadAll(w.Result().Body)
+	if w.Code != wantStatus {
+		t.Fatalf("%s %s: want status %d, got %d: %s", method, path, wantStatus, w.Code, data)
+	}
+	return data
+}
+
+func createSession(t *testing.T, srv http.Handler, body string) *SessionJSON {
+	t.Helper()
+	sj := &SessionJSON{}
+	if err := json.Unmarshal(request(t, srv, "POST", "/sessions", body, http.StatusCreated), sj); err != nil {
+		t.Fatalf("%s", err)
+	}
+	return sj
+}
+
+func TestSession(t *testing.T) {
+	srv := NewServer()
+	sj := createSession(t, srv, "")
+	if !sj.Resolve.Resolved {
+		t.Errorf("A squared set should be resolved: %#v", sj.Resolve)
+	}
+	path := "/sessions/" + sj.ID
+	state := SessionJSON{}
+	data := request(t, srv, "POST", path + "/calls", `{"calls": ["heads meet", "OriginalHeads QuarterLeft"]}`, http.StatusOK)
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := "OriginalHeads Meet,OriginalHeads QuarterLeft", strings.Join(state.Calls, ","); got != want {
+		t.Errorf("Wrong calls: want %q, got %q", want, got)
+	}
+	if state.Beats == 0 {
+		t.Errorf("No beats were danced")
+	}
+	// The Sides haven't moved:
+	if !strings.HasPrefix(state.Diagram, "2>") {
+		t.Errorf("Unexpected diagram:\n%s", state.Diagram)
+	}
+	request(t, srv, "POST", path + "/undo", "", http.StatusOK)
+	if err := json.Unmarshal(request(t, srv, "GET", path, "", http.StatusOK), &state); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := 1, len(state.Calls); got != want {
+		t.Errorf("Wrong number of calls after undo: want %d, got %d", want, got)
+	}
+	request(t, srv, "DELETE", path, "", http.StatusNoContent)
+	request(t, srv, "GET", path, "", http.StatusNotFound)
+}
+
+func TestCreateFromDiagram(t *testing.T) {
+	srv := NewServer()
+	sj := createSession(t, srv, "1v\n2u\n")
+	if want, got := 1, len(sj.Formations); got != want {
+		t.Fatalf("Wrong number of formations: want %d, got %d", want, got)
+	}
+	if want, got := "FaceToFace", sj.Formations[0].Type; got != want {
+		t.Errorf("Wrong formation: want %s, got %s", want, got)
+	}
+	request(t, srv, "POST", "/sessions", "1x\n", http.StatusBadRequest)
+}
+
+func TestCreateFromDancersJSON(t *testing.T) {
+	srv := NewServer()
+	dancers := notation.MustParse("1v\n2u\n")
+	data, err := json.Marshal(dancers)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	sj := createSession(t, srv, string(data))
+	if want, got := 1, len(sj.Formations); got != want {
+		t.Fatalf("Wrong number of formations: want %d, got %d", want, got)
+	}
+	// Dancers without a version aren't accepted:
+	bare, err := json.Marshal(dancer.NewDancersJSON(dancers))
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	request(t, srv, "POST", "/sessions", string(bare), http.StatusBadRequest)
+}
+
+func TestIllegalCall(t *testing.T) {
+	srv := NewServer()
+	path := "/sessions/" + createSession(t, srv, "").ID
+	data := request(t, srv, "POST", path + "/calls", `{"calls": ["heads meet", "PassToBacks"]}`,
+		http.StatusUnprocessableEntity)
+	ej := ErrorJSON{}
+	if err := json.Unmarshal(data, &ej); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if ej.Index == nil || *ej.Index != 1 {
+		t.Errorf("Wrong index: %s", data)
+	}
+	request(t, srv, "POST", path + "/calls", `{"calls": ["Frobnicate"]}`, http.StatusBadRequest)
+	state := SessionJSON{}
+	if err := json.Unmarshal(request(t, srv, "GET", path, "", http.StatusOK), &state); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(state.Calls) != 0 {
+		t.Errorf("Failed calls changed the session: %v", state.Calls)
+	}
+	request(t, srv, "POST", path + "/undo", "", http.StatusConflict)
+	request(t, srv, "GET", path + "/calls", "", http.StatusMethodNotAllowed)
+	request(t, srv, "GET", path + "/nothing", "", http.StatusNotFound)
+}
+
+func TestQueries(t *testing.T) {
+	srv := NewServer()
+	path := "/sessions/" + createSession(t, srv, "").ID
+	request(t, srv, "POST", path + "/calls", `{"calls": ["heads meet"]}`, http.StatusOK)
+	formations := struct {
+		Formations []struct{ Type string }
+	}{}
+	if err := json.Unmarshal(request(t, srv, "GET", path + "/formations", "", http.StatusOK), &formations); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(formations.Formations) == 0 {
+		t.Errorf("No formations")
+	}
+	roles := RolesJSON{}
+	if err := json.Unmarshal(request(t, srv, "GET", path + "/roles?role=OriginalHeads", "", http.StatusOK), &roles); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(roles.Roles) != 1 || fmt.Sprint(roles.Roles[0].Dancers) != "[0 1 4 5]" {
+		t.Errorf("Wrong OriginalHeads: %#v", roles)
+	}
+	if err := json.Unmarshal(request(t, srv, "GET", path + "/roles", "", http.StatusOK), &roles); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(roles.Roles) < 4 {
+		t.Errorf("Expected at least the Original and Current Heads and Sides: %#v", roles)
+	}
+	data := request(t, srv, "GET", path + "/timeline", "", http.StatusOK)
+	tl, err := timeline.UnmarshalTimeline(data, nil)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want, got := 8, len(tl.Dancers()); got != want {
+		t.Errorf("Wrong number of dancers in the timeline: want %d, got %d", want, got)
+	}
+	if svg := string(request(t, srv, "GET", path + "/timeline.svg", "", http.StatusOK)); !strings.Contains(svg, "<animateTransform") {
+		t.Errorf("The SVG isn't animated: %s", svg)
+	}
+	request(t, srv, "GET", path + "/timeline.html", "", http.StatusOK)
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	srv := NewServer()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		path := "/sessions/" + createSession(t, srv, "").ID
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				r := httptest.NewRequest("POST", path + "/calls", strings.NewReader(`{"calls": ["OriginalSides QuarterLeft"]}`))
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, r)
+				if w.Code != http.StatusOK {
+					t.Errorf("Wrong status %d", w.Code)
+				}
+				r = httptest.NewRequest("GET", path + "/roles", nil)
+				srv.ServeHTTP(httptest.NewRecorder(), r)
+			}
+		}()
+	}
+	wg.Wait()
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, s := range srv.sessions {
+		if want, got := 4, len(s.calls); got != want {
+			t.Errorf("Wrong number of calls: want %d, got %d", want, got)
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	srv := NewServer()
+	now := time.Now()
+	srv.now = func() time.Time { return now }
+	srv.maxSessions = 2
+	first := "/sessions/" + createSession(t, srv, "").ID
+	now = now.Add(time.Minute)
+	second := "/sessions/" + createSession(t, srv, "").ID
+	now = now.Add(time.Minute)
+	// Using the first session makes the second the least recently
+	// used:
+	request(t, srv, "GET", first, "", http.StatusOK)
+	now = now.Add(time.Minute)
+	third := "/sessions/" + createSession(t, srv, "").ID
+	request(t, srv, "GET", second, "", http.StatusNotFound)
+	request(t, srv, "GET", first, "", http.StatusOK)
+	request(t, srv, "GET", third, "", http.StatusOK)
+	now = now.Add(srv.idleTimeout + time.Second)
+	request(t, srv, "GET", first, "", http.StatusNotFound)
+	createSession(t, srv, "")
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if want, got := 1, len(srv.sessions); got != want {
+		t.Errorf("Idle sessions weren't forgotten: want %d, got %d", want, got)
+	}
+}
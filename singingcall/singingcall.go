@@ -0,0 +1,209 @@
+// Package singingcall checks that the choreography of a singing call
+// fits the music.
+//
+// A singing call is made up of seven parts.  The opener, break and
+// closer are usually patter style figures like Circle Left and
+// Allemande Left, and the four figures are danced with the caller
+// singing the words of the song.  Each part lasts for one musical
+// phrase of 64 beats.
+package singingcall
+
+import "bytes"
+import "fmt"
+import "sort"
+import "squaredance/action"
+
+
+// PhraseBeats is the length of a part of a singing call.
+const PhraseBeats = 64
+
+
+// PartKind identifies which part of a singing call a Part is.
+type PartKind int
+
+const (
+	Opener PartKind = iota
+	Figure
+	Break
+	Closer
+)
+
+func (k PartKind) String() string {
+	switch k {
+	case Opener:
+		return "Opener"
+	case Figure:
+		return "Figure"
+	case Break:
+		return "Break"
+	case Closer:
+		return "Closer"
+	}
+	return fmt.Sprintf("PartKind(%d)", int(k))
+}
+
+// StandardStructure is the order in which the parts of a singing call
+// occur.
+var StandardStructure = []PartKind{
+	Opener, Figure, Figure, Break, Figure, Figure, Closer,
+}
+
+
+// Part is one part of a singing call.
+type Part struct {
+	Kind PartKind
+	Calls []action.Action
+}
+
+// Beats returns how long the Part takes to dance.
+func (p Part) Beats() int {
+	return action.TotalBeats(p.Calls...)
+}
+
+
+// Fillers are the names of the Actions that a caller might add to a
+// Part that runs short.  Fillers are suggested in this order.  Each
+// must be the name of a defined Action.
+var Fillers = []string{
+	"Promenade",
+	"PromenadeThreeQuarters",
+	"PromenadeHalf",
+	"PromenadeQuarter",
+}
+
+
+// PartReport describes how well a Part fits its phrase.
+type PartReport struct {
+	Part Part
+	Beats int
+	// Slack is how many beats short of PhraseBeats the Part is.
+	// It is negative if the Part runs long.
+	Slack int
+	// Fillers suggests Actions which could be added to a Part
+	// that runs short.
+	Fillers []string
+}
+
+// OK returns true if the Part fits its phrase.
+func (r PartReport) OK() bool {
+	return r.Slack == 0
+}
+
+func (r PartReport) String() string {
+	switch {
+	case r.Slack > 0:
+		return fmt.Sprintf("%s is %d beats short, %d of %d; add %v",
+			r.Part.Kind, r.Slack, r.Beats, PhraseBeats, r.Fillers)
+	case r.Slack < 0:
+		return fmt.Sprintf("%s is %d beats long, %d of %d",
+			r.Part.Kind, -r.Slack, r.Beats, PhraseBeats)
+	}
+	return fmt.Sprintf("%s fits, %d beats", r.Part.Kind, r.Beats)
+}
+
+
+// Report describes how well a singing call fits the music.
+type Report struct {
+	Parts []PartReport
+	// StructureProblems describes any ways that the Parts differ
+	// from StandardStructure.
+	StructureProblems []string
+}
+
+// OK returns true if the singing call has the standard structure and
+// every Part fits its phrase.
+func (r *Report) OK() bool {
+	if len(r.StructureProblems) > 0 {
+		return false
+	}
+	for _, p := range r.Parts {
+		if !p.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Report) String() string {
+	buf := bytes.NewBufferString("")
+	for _, problem := range r.StructureProblems {
+		fmt.Fprintf(buf, "%s\n", problem)
+	}
+	for i, p := range r.Parts {
+		fmt.Fprintf(buf, "%d: %s\n", i + 1, p)
+	}
+	return buf.String()
+}
+
+
+// Check checks the Parts of a singing call against StandardStructure
+// and PhraseBeats.
+func Check(parts ...Part) *Report {
+	report := &Report{
+		Parts: []PartReport{},
+		StructureProblems: []string{},
+	}
+	if len(parts) != len(StandardStructure) {
+		report.StructureProblems = append(report.StructureProblems,
+			fmt.Sprintf("A singing call has %d parts, not %d",
+				len(StandardStructure), len(parts)))
+	}
+	for i, part := range parts {
+		if i < len(StandardStructure) && part.Kind != StandardStructure[i] {
+			report.StructureProblems = append(report.StructureProblems,
+				fmt.Sprintf("Part %d should be the %s, not the %s",
+					i + 1, StandardStructure[i], part.Kind))
+		}
+		beats := part.Beats()
+		pr := PartReport{
+			Part: part,
+			Beats: beats,
+			Slack: PhraseBeats - beats,
+			Fillers: []string{},
+		}
+		if pr.Slack > 0 {
+			pr.Fillers = SuggestFillers(pr.Slack)
+		}
+		report.Parts = append(report.Parts, pr)
+	}
+	return report
+}
+
+
+// SuggestFillers returns the names of the fewest Fillers whose beats
+// add up to as much of beats as possible without going over.
+func SuggestFillers(beats int) []string {
+	type filler struct {
+		name string
+		beats int
+	}
+	fillers := []filler{}
+	for _, name := range Fillers {
+		fillers = append(fillers, filler{ name, action.StandardBeats(name) })
+	}
+	// best[b] is the shortest list of fillers that exactly fills b
+	// beats, or nil if none does.
+	best := make([][]string, beats + 1)
+	best[0] = []string{}
+	for b := 1; b <= beats; b++ {
+		for _, f := range fillers {
+			if f.beats > b || best[b - f.beats] == nil {
+				continue
+			}
+			if best[b] == nil || len(best[b - f.beats]) + 1 < len(best[b]) {
+				best[b] = append(append([]string{}, best[b - f.beats]...), f.name)
+			}
+		}
+	}
+	for b := beats; b >= 0; b-- {
+		if best[b] != nil {
+			// Longest fillers first:
+			suggestion := best[b]
+			sort.SliceStable(suggestion, func(i, j int) bool {
+				return action.StandardBeats(suggestion[i]) > action.StandardBeats(suggestion[j])
+			})
+			return suggestion
+		}
+	}
+	return []string{}
+}
@@ -0,0 +1,70 @@
+package singingcall
+
+import "reflect"
+import "testing"
+import "squaredance/action"
+import "squaredance/sequence"
+
+
+// calls returns count repetitions of the named Action.
+func calls(name string, count int) []action.Action {
+	a := action.FindAction(name)
+	result := []action.Action{}
+	for i := 0; i < count; i++ {
+		result = append(result, a)
+	}
+	return result
+}
+
+func TestSuggestFillers(t *testing.T) {
+	if want, got := []string{ "Promenade", "PromenadeQuarter" }, SuggestFillers(20); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	// Promenades come in 4 beat chunks, so the best we can do is 8:
+	if want, got := []string{ "PromenadeHalf" }, SuggestFillers(10); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// Meet takes 2 beats.
+	parts := []Part{}
+	for _, kind := range StandardStructure {
+		parts = append(parts, Part{ Kind: kind, Calls: calls("Meet", 32) })
+	}
+	if report := Check(parts...); !report.OK() {
+		t.Errorf("Expected a good fit:\n%s", report)
+	}
+	parts[1].Calls = calls("Meet", 28)
+	parts[2].Calls = calls("Meet", 33)
+	report := Check(parts...)
+	if report.OK() {
+		t.Fatalf("Expected problems")
+	}
+	if want, got := 8, report.Parts[1].Slack; got != want {
+		t.Errorf("Wrong slack: want %d, got %d", want, got)
+	}
+	if want, got := []string{ "PromenadeHalf" }, report.Parts[1].Fillers; !reflect.DeepEqual(want, got) {
+		t.Errorf("Wrong fillers: want %v, got %v", want, got)
+	}
+	if want, got := -2, report.Parts[2].Slack; got != want {
+		t.Errorf("Wrong slack: want %d, got %d", want, got)
+	}
+	report = Check(parts[1:]...)
+	if want, got := 5, len(report.StructureProblems); got != want {
+		t.Errorf("Wrong number of structure problems: want %d, got %d:\n%s", want, got, report)
+	}
+}
+
+func TestFillersAreCalls(t *testing.T) {
+	for _, name := range Fillers {
+		call, err := sequence.ParseCall(name)
+		if err != nil {
+			t.Errorf("Filler %s: %s", name, err)
+			continue
+		}
+		if want, got := action.StandardBeats(name), call.Action.Beats(); got != want {
+			t.Errorf("Filler %s: want %d beats, got %d", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Animating a Timeline.
+
+package svg
+
+import "bytes"
+import "fmt"
+import "html/template"
+import "io"
+import "strings"
+import "time"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+
+
+// DefaultTickDuration is how long each timeline.Time lasts in an
+// animation if AnimationOptions.TickDuration is 0.  It is one beat at
+// 120 beats per minute.
+const DefaultTickDuration = 500 * time.Millisecond
+
+
+// AnimationOptions control how a Timeline is animated.
+type AnimationOptions struct {
+	Options
+	// TickDuration is how long each timeline.Time lasts.
+	TickDuration time.Duration
+	// Repeat makes the animation start over when it ends.
+	Repeat bool
+}
+
+func (options *AnimationOptions) defaults() {
+	if options.Scale <= 0 {
+		options.Scale = DefaultScale
+	}
+	if options.TickDuration <= 0 {
+		options.TickDuration = DefaultTickDuration
+	}
+}
+
+
+// Animate returns an SVG image in which the dancers of the Timeline
+// move, using SMIL animation, as the Timeline describes.  Between
+// snapshots each dancer moves in a straight line and turns whichever
+// way is shorter.
+func Animate(tl timeline.Timeline, options AnimationOptions) string {
+	options.defaults()
+	left, right, down, up := tl.Bounds()
+	b := newBounds(options.Center,
+		geometry.NewPositionDownLeft(down, left),
+		geometry.NewPositionDownLeft(up, right))
+	times := timeline.Times(tl)
+	buf := bytes.NewBufferString("")
+	writeStart(buf, b, options.Options)
+	for _, d := range tl.Dancers() {
+		writeAnimatedDancer(buf, tl, d, times, options)
+	}
+	fmt.Fprintf(buf, "</g></svg>")
+	return buf.String()
+}
+
+// WriteAnimation writes an animated SVG image of the Timeline to w.
+func WriteAnimation(w io.Writer, tl timeline.Timeline, options AnimationOptions) error {
+	_, err := io.WriteString(w, Animate(tl, options))
+	return err
+}
+
+// writeAnimatedDancer draws the Dancer at the origin, facing
+// Direction0, and then animates its translation and rotation.  The
+// label is translated but not rotated so that it stays upright.
+func writeAnimatedDancer(buf *bytes.Buffer, tl timeline.Timeline, d dancer.Dancer, times []timeline.Time, options AnimationOptions) {
+	positions := []geometry.Position{}
+	directions := []geometry.Direction{}
+	for _, t := range times {
+		p, dir, ok := timeline.Interpolate(tl, d, float32(t))
+		if !ok {
+			p, dir = d.Position(), d.Direction()
+		}
+		positions = append(positions, p)
+		directions = append(directions, dir)
+	}
+	if len(positions) == 0 {
+		positions = append(positions, d.Position())
+		directions = append(directions, d.Direction())
+	}
+	translations := []string{}
+	for _, p := range positions {
+		translations = append(translations,
+			number(float64(p.Left)) + " " + number(float64(p.Down)))
+	}
+	rotations := []string{}
+	for _, turns := range unwrap(directions) {
+		rotations = append(rotations, number(rotation(turns)))
+	}
+	fmt.Fprintf(buf, `<g class="dancer" transform="translate(%s)">`, translations[0])
+	writeTitle(buf, d)
+	fmt.Fprintf(buf, `<g transform="rotate(%s)">`, rotations[0])
+	writeBody(buf, d, 0, 0, 0, dancerSize / 2)
+	writeAnimateTransform(buf, "rotate", rotations, times, options)
+	fmt.Fprintf(buf, "</g>")
+	writeLabel(buf, d, options.Label, 0, 0)
+	writeAnimateTransform(buf, "translate", translations, times, options)
+	fmt.Fprintf(buf, "</g>")
+}
+
+// rotation returns the SVG rotation, in degrees clockwise, that turns
+// something facing Direction0 to face the Direction which is turns
+// counterclockwise from it.
+func rotation(turns float32) float64 {
+	return -360 * float64(turns)
+}
+
+// unwrap returns the directions as a continuous sequence of turns
+// where each differs from the previous one by the shorter way around.
+func unwrap(directions []geometry.Direction) []float32 {
+	turns := []float32{}
+	for i, d := range directions {
+		if i == 0 {
+			turns = append(turns, float32(d))
+			continue
+		}
+		turns = append(turns, turns[i - 1] + float32(d.Subtract(directions[i - 1])))
+	}
+	return turns
+}
+
+func writeAnimateTransform(buf *bytes.Buffer, kind string, values []string, times []timeline.Time, options AnimationOptions) {
+	if len(times) < 2 {
+		return
+	}
+	first, last := times[0], times[len(times) - 1]
+	keyTimes := []string{}
+	for _, t := range times {
+		keyTimes = append(keyTimes, number(float64(t - first) / float64(last - first)))
+	}
+	repeat := "1"
+	if options.Repeat {
+		repeat = "indefinite"
+	}
+	fmt.Fprintf(buf, `<animateTransform attributeName="transform" type="%s" values="%s" keyTimes="%s" dur="%s" begin="0s" fill="freeze" repeatCount="%s"/>`,
+		kind,
+		strings.Join(values, ";"),
+		strings.Join(keyTimes, ";"),
+		smilDuration(time.Duration(last - first) * options.TickDuration),
+		repeat)
+}
+
+func smilDuration(d time.Duration) string {
+	return number(d.Seconds()) + "s"
+}
+
+
+// WriteAnimationPage writes a self contained HTML page which plays the
+// animation of the Timeline, with controls to play, pause and step
+// through it one timeline.Time at a time.
+func WriteAnimationPage(w io.Writer, title string, tl timeline.Timeline, options AnimationOptions) error {
+	options.defaults()
+	options.Repeat = false
+	if options.ID == "" {
+		options.ID = "animation"
+	}
+	times := timeline.Times(tl)
+	first, last := timeline.Time(0), timeline.Time(0)
+	if len(times) > 0 {
+		first, last = times[0], times[len(times) - 1]
+	}
+	return animation_page.Execute(w, map[string]interface{}{
+		"Title": title,
+		"ID": options.ID,
+		"SVG": template.HTML(Animate(tl, options)),
+		"Step": options.TickDuration.Seconds(),
+		"Duration": (time.Duration(last - first) * options.TickDuration).Seconds(),
+	})
+}
+
+var animation_page = template.Must(template.New("animation_page").Parse(`<html>
+  <head>
+    <title>{{.Title}}</title>
+    <style>
+svg {
+    background-color: lightslategray;
+}
+    </style>
+  </head>
+  <body>
+    <h1>{{.Title}}</h1>
+    <div>{{.SVG}}</div>
+    <div>
+      <button id="play">Play</button>
+      <button id="pause">Pause</button>
+      <button id="back">Step back</button>
+      <button id="forward">Step forward</button>
+      <button id="restart">Restart</button>
+      <span id="time"></span>
+    </div>
+    <script type="text/javascript">
+(function() {
+  var svg = document.getElementById({{.ID}});
+  var step = {{.Step}};
+  var duration = {{.Duration}};
+  var show = function() {
+    var t = Math.min(svg.getCurrentTime(), duration);
+    document.getElementById("time").textContent =
+      (t / step).toFixed(1) + " / " + (duration / step).toFixed(0);
+  };
+  var seek = function(t) {
+    svg.pauseAnimations();
+    svg.setCurrentTime(Math.max(0, Math.min(duration, t)));
+    show();
+  };
+  document.getElementById("play").onclick = function() {
+    if (svg.getCurrentTime() >= duration) {
+      svg.setCurrentTime(0);
+    }
+    svg.unpauseAnimations();
+  };
+  document.getElementById("pause").onclick = function() {
+    svg.pauseAnimations();
+    show();
+  };
+  document.getElementById("back").onclick = function() {
+    seek(Math.ceil(svg.getCurrentTime() / step - 1.0001) * step);
+  };
+  document.getElementById("forward").onclick = function() {
+    seek(Math.floor(svg.getCurrentTime() / step + 1.0001) * step);
+  };
+  document.getElementById("restart").onclick = function() {
+    seek(0);
+  };
+  svg.pauseAnimations();
+  svg.setCurrentTime(0);
+  setInterval(show, 100);
+})();
+    </script>
+  </body>
+</html>
+`))
@@ -0,0 +1,55 @@
+package svg
+
+import "bytes"
+import "math"
+import "strings"
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+import "squaredance/timeline"
+
+
+func TestUnwrap(t *testing.T) {
+	turns := unwrap([]geometry.Direction{ 0.4, -0.4, 0.1 })
+	// 0.4 to -0.4 is shorter going counterclockwise through 0.5:
+	want := []float32{ 0.4, 0.6, 1.1 }
+	for i, w := range want {
+		if math.Abs(float64(turns[i] - w)) > 0.0001 {
+			t.Errorf("unwrap: want %v, got %v", want, turns)
+			break
+		}
+	}
+}
+
+func TestAnimate(t *testing.T) {
+	set := dancer.NewSquaredSet(2)
+	tl := timeline.NewTimeline(set.Dancers())
+	tl.MakeSnapshot(0)
+	for _, d := range set.Dancers() {
+		d.Move(d.Position().Add(geometry.NewPosition(d.Direction(), 1)),
+			d.Direction().QuarterLeft())
+	}
+	tl.MakeSnapshot(2)
+	out := Animate(tl, AnimationOptions{})
+	if want, got := 2 * len(set.Dancers()), strings.Count(out, "<animateTransform"); got != want {
+		t.Errorf("Wrong number of animations: want %d, got %d", want, got)
+	}
+	if !strings.Contains(out, `keyTimes="0;1"`) {
+		t.Errorf("Missing keyTimes:\n%s", out)
+	}
+	if !strings.Contains(out, `dur="1s"`) {
+		t.Errorf("Wrong duration:\n%s", out)
+	}
+	// Couple 1 starts facing Direction0 and quarters left:
+	if !strings.Contains(out, `values="0;-90"`) {
+		t.Errorf("Missing rotation:\n%s", out)
+	}
+	page := bytes.NewBufferString("")
+	if err := WriteAnimationPage(page, "Test", tl, AnimationOptions{}); err != nil {
+		t.Fatalf("WriteAnimationPage: %s", err)
+	}
+	if !strings.Contains(page.String(), "<animateTransform") ||
+		!strings.Contains(page.String(), "pauseAnimations") {
+		t.Errorf("Bad animation page:\n%s", page)
+	}
+}
@@ -0,0 +1,241 @@
+// Package svg draws dancers as standalone SVG images.
+//
+// A Position's Left coordinate is drawn along the x axis and its Down
+// coordinate along the y axis, so a dancer facing Direction0 faces
+// down the page.  Guys are drawn as squares and gals as circles.  Each
+// dancer has a nose on the side it is facing.
+package svg
+
+import "bytes"
+import "fmt"
+import "html"
+import "io"
+import "math"
+import "strconv"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+// DefaultScale is the number of pixels per geometry.CoupleDistance
+// that is used if Options.Scale is 0.
+const DefaultScale = 40
+
+// Sizes of things, in the same units as geometry.Position:
+const (
+	// dancerSize is the width of a guy's square and the diameter
+	// of a gal's circle.
+	dancerSize = 0.6
+	noseSize = 0.12
+	margin = 0.75
+	strokeWidth = 0.03
+	fontSize = 0.3
+	centerSize = 0.15
+)
+
+
+// Label says what text is drawn on each dancer.
+type Label int
+
+const (
+	// CoupleLabel labels each dancer with its couple number, or
+	// its ordinal if it has no couple number.
+	CoupleLabel Label = iota
+	OrdinalLabel
+	NoLabel
+)
+
+
+// Options control how the dancers are drawn.
+type Options struct {
+	// ID, if not empty, is the id attribute of the svg element.
+	ID string
+	// Scale is the number of pixels per geometry.CoupleDistance.
+	Scale float32
+	Label Label
+	// Grid draws lines every half geometry.CoupleDistance, aligned
+	// with Center if there is one.
+	Grid bool
+	// Center, if not nil, is drawn as the flagpole center of the
+	// set.
+	Center *geometry.Position
+}
+
+
+// bounds is the area of the floor that is drawn.
+type bounds struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (b *bounds) include(p geometry.Position) {
+	x, y := float64(p.Left), float64(p.Down)
+	b.minX = math.Min(b.minX, x)
+	b.minY = math.Min(b.minY, y)
+	b.maxX = math.Max(b.maxX, x)
+	b.maxY = math.Max(b.maxY, y)
+}
+
+// newBounds returns bounds which include the positions and center,
+// if it isn't nil, with a margin around them.
+func newBounds(center *geometry.Position, positions ...geometry.Position) *bounds {
+	b := &bounds{
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+	}
+	for _, p := range positions {
+		b.include(p)
+	}
+	if center != nil {
+		b.include(*center)
+	}
+	if math.IsInf(b.minX, 1) {
+		b.include(geometry.Position{})
+	}
+	b.minX -= margin
+	b.minY -= margin
+	b.maxX += margin
+	b.maxY += margin
+	return b
+}
+
+
+// Render returns an SVG image of the dancers.
+func Render(dancers dancer.Dancers, options Options) string {
+	if options.Scale <= 0 {
+		options.Scale = DefaultScale
+	}
+	positions := []geometry.Position{}
+	for _, d := range dancers {
+		positions = append(positions, d.Position())
+	}
+	b := newBounds(options.Center, positions...)
+	buf := bytes.NewBufferString("")
+	writeStart(buf, b, options)
+	for _, d := range dancers {
+		writeDancer(buf, d, options.Label)
+	}
+	fmt.Fprintf(buf, "</g></svg>")
+	return buf.String()
+}
+
+// writeStart writes the start of the svg element, and the grid and
+// flagpole center if the Options call for them.
+func writeStart(buf *bytes.Buffer, b *bounds, options Options) {
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg"`)
+	if options.ID != "" {
+		fmt.Fprintf(buf, ` id="%s"`, options.ID)
+	}
+	fmt.Fprintf(buf, ` width="%.0f" height="%.0f" viewBox="%s %s %s %s">`,
+		(b.maxX - b.minX) * float64(options.Scale),
+		(b.maxY - b.minY) * float64(options.Scale),
+		number(b.minX), number(b.minY),
+		number(b.maxX - b.minX), number(b.maxY - b.minY))
+	fmt.Fprintf(buf, `<g stroke="black" stroke-width="%s">`, number(strokeWidth))
+	if options.Grid {
+		writeGrid(buf, b, options.Center)
+	}
+	if options.Center != nil {
+		writeCenter(buf, *options.Center)
+	}
+}
+
+// Write writes an SVG image of the dancers to w.
+func Write(w io.Writer, dancers dancer.Dancers, options Options) error {
+	_, err := io.WriteString(w, Render(dancers, options))
+	return err
+}
+
+
+// number formats a coordinate compactly.
+func number(f float64) string {
+	f = math.Round(f * 1000) / 1000
+	if f == 0 {
+		// Avoid "-0".
+		f = 0
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func writeGrid(buf *bytes.Buffer, b *bounds, center *geometry.Position) {
+	spacing := float64(geometry.CoupleDistance) / 2
+	origin := geometry.Position{}
+	if center != nil {
+		origin = *center
+	}
+	fmt.Fprintf(buf, `<g class="grid" stroke="lightgray" stroke-width="%s">`,
+		number(strokeWidth / 2))
+	start := func(min, o float64) float64 {
+		return o + math.Ceil((min - o) / spacing) * spacing
+	}
+	for x := start(b.minX, float64(origin.Left)); x <= b.maxX; x += spacing {
+		fmt.Fprintf(buf, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`,
+			number(x), number(b.minY), number(x), number(b.maxY))
+	}
+	for y := start(b.minY, float64(origin.Down)); y <= b.maxY; y += spacing {
+		fmt.Fprintf(buf, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`,
+			number(b.minX), number(y), number(b.maxX), number(y))
+	}
+	fmt.Fprintf(buf, "</g>")
+}
+
+func writeCenter(buf *bytes.Buffer, center geometry.Position) {
+	x, y := float64(center.Left), float64(center.Down)
+	fmt.Fprintf(buf, `<g class="center">`)
+	fmt.Fprintf(buf, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`,
+		number(x - centerSize), number(y), number(x + centerSize), number(y))
+	fmt.Fprintf(buf, `<line x1="%s" y1="%s" x2="%s" y2="%s"/>`,
+		number(x), number(y - centerSize), number(x), number(y + centerSize))
+	fmt.Fprintf(buf, "</g>")
+}
+
+func writeDancer(buf *bytes.Buffer, d dancer.Dancer, label Label) {
+	p := d.Position()
+	x, y := float64(p.Left), float64(p.Down)
+	nose := p.Add(geometry.NewPosition(d.Direction(), dancerSize / 2))
+	fmt.Fprintf(buf, `<g class="dancer">`)
+	writeTitle(buf, d)
+	writeBody(buf, d, x, y, float64(nose.Left), float64(nose.Down))
+	writeLabel(buf, d, label, x, y)
+	fmt.Fprintf(buf, "</g>")
+}
+
+func writeTitle(buf *bytes.Buffer, d dancer.Dancer) {
+	fmt.Fprintf(buf, "<title>%s</title>", html.EscapeString(fmt.Sprint(d)))
+}
+
+// writeBody draws the Dancer centered at x, y with its nose at noseX,
+// noseY.
+func writeBody(buf *bytes.Buffer, d dancer.Dancer, x, y, noseX, noseY float64) {
+	fmt.Fprintf(buf, `<circle class="nose" cx="%s" cy="%s" r="%s" fill="black"/>`,
+		number(noseX), number(noseY), number(noseSize))
+	switch d.Gender() {
+	case dancer.Guy:
+		fmt.Fprintf(buf, `<rect class="guy" x="%s" y="%s" width="%s" height="%s" fill="white"/>`,
+			number(x - dancerSize / 2), number(y - dancerSize / 2),
+			number(dancerSize), number(dancerSize))
+	default:
+		// Gals and dancers of unspecified gender are drawn as
+		// circles.
+		fmt.Fprintf(buf, `<circle class="gal" cx="%s" cy="%s" r="%s" fill="white"/>`,
+			number(x), number(y), number(dancerSize / 2))
+	}
+}
+
+func writeLabel(buf *bytes.Buffer, d dancer.Dancer, label Label, x, y float64) {
+	text := ""
+	switch label {
+	case CoupleLabel:
+		if d.CoupleNumber() > 0 {
+			text = fmt.Sprintf("%d", d.CoupleNumber())
+		} else {
+			text = fmt.Sprintf("%d", d.Ordinal())
+		}
+	case OrdinalLabel:
+		text = fmt.Sprintf("%d", d.Ordinal())
+	}
+	if text != "" {
+		fmt.Fprintf(buf, `<text x="%s" y="%s" font-size="%s" stroke="none" text-anchor="middle" dominant-baseline="central">%s</text>`,
+			number(x), number(y), number(fontSize), text)
+	}
+}
@@ -0,0 +1,57 @@
+package svg
+
+import "encoding/xml"
+import "io"
+import "strings"
+import "testing"
+import "squaredance/dancer"
+
+
+func TestRenderSquaredSet(t *testing.T) {
+	set := dancer.NewSquaredSet(4)
+	center := set.FlagpoleCenter()
+	out := Render(set.Dancers(), Options{
+		ID: "set",
+		Grid: true,
+		Center: &center,
+	})
+	// The output should be well formed XML:
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("Bad SVG: %s\n%s", err, out)
+			}
+			break
+		}
+	}
+	for class, want := range map[string]int{
+		"guy": 4,
+		"gal": 4,
+		"nose": 8,
+		"center": 1,
+		"grid": 1,
+	} {
+		if got := strings.Count(out, `class="` + class + `"`); got != want {
+			t.Errorf("Wrong number of %s elements: want %d, got %d", class, want, got)
+		}
+	}
+	if !strings.Contains(out, `id="set"`) {
+		t.Errorf("No id attribute")
+	}
+}
+
+func TestRenderLabels(t *testing.T) {
+	dancers := dancer.NewSquaredSet(2).Dancers()
+	if out := Render(dancers, Options{ Label: NoLabel }); strings.Contains(out, "<text") {
+		t.Errorf("NoLabel should draw no text")
+	}
+	out := Render(dancers, Options{ Label: OrdinalLabel })
+	if !strings.Contains(out, ">3</text>") {
+		t.Errorf("Ordinal 3 not labeled")
+	}
+	if strings.Contains(out, `class="center"`) || strings.Contains(out, `class="grid"`) {
+		t.Errorf("Center and grid should be optional")
+	}
+}
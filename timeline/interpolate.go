@@ -0,0 +1,64 @@
+// Finding where dancers are between snapshots.
+
+package timeline
+
+import "sort"
+import "squaredance/geometry"
+import "squaredance/dancer"
+
+
+// Times returns the distinct Times at which the Timeline has
+// snapshots, in increasing order.
+func Times(tl Timeline) []Time {
+	seen := map[Time]bool{}
+	times := []Time{}
+	tl.DoSnapshots(func(s DancerSnapshot) bool {
+		if !seen[s.Time()] {
+			seen[s.Time()] = true
+			times = append(times, s.Time())
+		}
+		return true
+	})
+	sort.Slice(times, func(i, j int) bool {
+		return times[i] < times[j]
+	})
+	return times
+}
+
+// Interpolate returns where the Dancer is at time t according to the
+// Timeline.  Between snapshots the Dancer moves in a straight line
+// and turns whichever way is shorter.  Before its first snapshot the
+// Dancer is where that snapshot says and after its last snapshot it
+// is where that one says.  Interpolate returns false if there are no
+// snapshots of the Dancer.
+func Interpolate(tl Timeline, d dancer.Dancer, t float32) (geometry.Position, geometry.Direction, bool) {
+	snapshots := tl.FindSnapshots(d, -1, tl.MostRecent() + 1)
+	if len(snapshots) == 0 {
+		return geometry.Position{}, geometry.Direction0, false
+	}
+	// Stable, so that of two snapshots at the same Time the later
+	// one recorded wins.
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return snapshots[i].Time() < snapshots[j].Time()
+	})
+	before := snapshots[0]
+	if t <= float32(before.Time()) {
+		return before.Position(), before.Direction(), true
+	}
+	for _, after := range snapshots[1:] {
+		if t < float32(after.Time()) {
+			if after.Time() == before.Time() {
+				break
+			}
+			fraction := (t - float32(before.Time())) /
+				float32(after.Time() - before.Time())
+			position, direction := Straight{
+				To: after.Position(),
+				Facing: after.Direction(),
+			}.At(before.Position(), before.Direction(), fraction)
+			return position, direction, true
+		}
+		before = after
+	}
+	return before.Position(), before.Direction(), true
+}
@@ -0,0 +1,33 @@
+package timeline
+
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+func TestInterpolate(t *testing.T) {
+	d := dancer.MakeSomeDancers(1)[0]
+	tl := NewTimeline(dancer.Dancers{ d })
+	d.Move(geometry.NewPositionDownLeft(0, 0), geometry.Direction(0.4))
+	tl.MakeSnapshot(1)
+	d.Move(geometry.NewPositionDownLeft(2, -2), geometry.Direction(-0.4))
+	tl.MakeSnapshot(3)
+	check := func(time float32, position geometry.Position, direction geometry.Direction) {
+		p, dir, ok := Interpolate(tl, d, time)
+		if !ok || !p.Equal(position) || !dir.Equal(direction) {
+			t.Errorf("At %f: want %v %v, got %v %v %v",
+				time, position, direction, p, dir, ok)
+		}
+	}
+	check(0, geometry.NewPositionDownLeft(0, 0), geometry.Direction(0.4))
+	// Turning the shorter way, through 0.5:
+	check(2, geometry.NewPositionDownLeft(1, -1), geometry.Direction(0.5))
+	check(5, geometry.NewPositionDownLeft(2, -2), geometry.Direction(-0.4))
+	if want, got := []Time{ 1, 3 }, Times(tl); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Times: want %v, got %v", want, got)
+	}
+	left, right, down, up := tl.Bounds()
+	if left != 0 || right != -2 || down != 2 || up != 0 {
+		t.Errorf("Wrong Bounds: %v %v %v %v", left, right, down, up)
+	}
+}
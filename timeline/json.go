@@ -0,0 +1,93 @@
+// Saving and loading Timelines as JSON.
+
+package timeline
+
+import "encoding/json"
+import "fmt"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+// SnapshotJSON is the JSON encoding of a DancerSnapshot.  The Dancer
+// is identified by its Ordinal.
+type SnapshotJSON struct {
+	Time Time                    `json:"time"`
+	Dancer int                   `json:"dancer"`
+	Down float32                 `json:"down"`
+	Left float32                 `json:"left"`
+	Direction float32            `json:"direction"`
+}
+
+// TimelineJSON is the JSON encoding of a Timeline.
+type TimelineJSON struct {
+	Version int                  `json:"version"`
+	Dancers []dancer.DancerJSON  `json:"dancers"`
+	Snapshots []SnapshotJSON     `json:"snapshots"`
+}
+
+// MarshalJSON encodes the Timeline as a TimelineJSON.
+func (tl *TimelineImpl) MarshalJSON() ([]byte, error) {
+	tj := TimelineJSON{
+		Version: dancer.JSONVersion,
+		Dancers: dancer.NewDancersJSON(tl.Dancers()),
+		Snapshots: []SnapshotJSON{},
+	}
+	tl.DoSnapshots(func(s DancerSnapshot) bool {
+		tj.Snapshots = append(tj.Snapshots, SnapshotJSON{
+			Time: s.Time(),
+			Dancer: s.Dancer().Ordinal(),
+			Down: float32(s.Position().Down),
+			Left: float32(s.Position().Left),
+			Direction: float32(s.Direction()),
+		})
+		return true
+	})
+	return json.Marshal(tj)
+}
+
+// UnmarshalJSON decodes a Timeline that was encoded by MarshalJSON.
+// The Timeline's Dancers are new Dancers, not in any Set.
+func (tl *TimelineImpl) UnmarshalJSON(data []byte) error {
+	decoded, err := UnmarshalTimeline(data, nil)
+	if err != nil {
+		return err
+	}
+	*tl = *decoded.(*TimelineImpl)
+	return nil
+}
+
+// UnmarshalTimeline decodes a Timeline that was encoded by
+// MarshalJSON.  If dancers is nil the Timeline's Dancers are decoded
+// too.  Otherwise the snapshots are matched to the dancers by
+// Ordinal, so that a Timeline can be reattached to the Dancers of a
+// Set that was saved along with it.
+func UnmarshalTimeline(data []byte, dancers dancer.Dancers) (Timeline, error) {
+	tj := TimelineJSON{}
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return nil, err
+	}
+	if err := dancer.CheckJSONVersion(tj.Version); err != nil {
+		return nil, err
+	}
+	if dancers == nil {
+		var err error
+		if dancers, err = dancer.DancersFromJSON(tj.Dancers); err != nil {
+			return nil, err
+		}
+	}
+	byOrdinal := map[int]dancer.Dancer{}
+	for _, d := range dancers {
+		byOrdinal[d.Ordinal()] = d
+	}
+	tl := NewTimeline(dancers)
+	for _, sj := range tj.Snapshots {
+		d, ok := byOrdinal[sj.Dancer]
+		if !ok {
+			return nil, fmt.Errorf("Snapshot of unknown dancer %d", sj.Dancer)
+		}
+		tl.RecordSnapshot(d, sj.Time,
+			geometry.NewPositionDownLeft(geometry.Down(sj.Down), geometry.Left(sj.Left)),
+			geometry.Direction(sj.Direction))
+	}
+	return tl, nil
+}
@@ -0,0 +1,47 @@
+package timeline
+
+import "encoding/json"
+import "testing"
+import "squaredance/dancer"
+
+
+func TestTimelineJSON(t *testing.T) {
+	set := dancer.NewSquaredSet(2)
+	tl := NewTimeline(set.Dancers())
+	tl.MakeSnapshot(0)
+	set.Dancers()[0].Rotate(0.25)
+	tl.MakeSnapshot(1)
+	data, err := json.Marshal(tl)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+	check := func(decoded Timeline) {
+		if decoded.MostRecent() != 1 {
+			t.Errorf("Wrong MostRecent: %d", decoded.MostRecent())
+		}
+		for i, d := range tl.Dancers() {
+			d2 := decoded.Dancers()[i]
+			for time := Time(0); time <= 1; time++ {
+				s1, s2 := tl.FindSnapshot(d, time), decoded.FindSnapshot(d2, time)
+				if s2 == nil || !s1.Position().Equal(s2.Position()) ||
+					!s1.Direction().Equal(s2.Direction()) {
+					t.Errorf("Wrong snapshot of %v at %d: %v", d2, time, s2)
+				}
+			}
+		}
+	}
+	decoded := &TimelineImpl{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+	check(decoded)
+	// Reattach to the original dancers:
+	reattached, err := UnmarshalTimeline(data, set.Dancers())
+	if err != nil {
+		t.Fatalf("UnmarshalTimeline: %s", err)
+	}
+	if reattached.Dancers()[0] != set.Dancers()[0] {
+		t.Errorf("Not reattached")
+	}
+	check(reattached)
+}
@@ -0,0 +1,215 @@
+// Describing how a dancer gets from one place to another.
+
+package timeline
+
+import "math"
+import "squaredance/geometry"
+import "squaredance/dancer"
+
+
+// DefaultStepBeats is how many beats a PathStep takes if no duration
+// is specified.
+const DefaultStepBeats float32 = 1
+
+
+// PathStep is one piece of a dancer's motion.
+type PathStep interface {
+	// Beats returns how long the step takes.
+	Beats() float32
+	// At returns where a dancer who started the step at position,
+	// facing direction, would be after the specified fraction, from
+	// 0 to 1, of the step.
+	At(position geometry.Position, direction geometry.Direction, fraction float32) (geometry.Position, geometry.Direction)
+}
+
+
+// Straight is a PathStep where the dancer walks in a straight line to
+// To, turning smoothly to face Facing along the way.
+type Straight struct {
+	To geometry.Position
+	Facing geometry.Direction
+	Duration float32
+}
+
+func (s Straight) Beats() float32 { return s.Duration }
+
+func (s Straight) At(position geometry.Position, direction geometry.Direction, fraction float32) (geometry.Position, geometry.Direction) {
+	return position.Add(s.To.Subtract(position).Scale(fraction)),
+		direction.Add(s.Facing.Subtract(direction).MultiplyBy(fraction))
+}
+
+
+// Arc is a PathStep where the dancer moves around Pivot, turning by
+// Turn as it goes.  Positive Turns are counterclockwise, in promenade
+// direction.  The dancer's facing direction turns along with it.
+type Arc struct {
+	Pivot geometry.Position
+	// Turn isn't canonicalized, so an Arc can go more than half
+	// way around.
+	Turn float32
+	Duration float32
+}
+
+func (a Arc) Beats() float32 { return a.Duration }
+
+func (a Arc) At(position geometry.Position, direction geometry.Direction, fraction float32) (geometry.Position, geometry.Direction) {
+	rotation := geometry.Direction(a.Turn * fraction)
+	return a.Pivot.Add(position.Subtract(a.Pivot).Rotate(rotation)),
+		direction.Add(rotation)
+}
+
+
+// TurnInPlace is a PathStep where the dancer stays put and turns by
+// Turn.  Positive Turns are to the left.
+type TurnInPlace struct {
+	Turn float32
+	Duration float32
+}
+
+func (t TurnInPlace) Beats() float32 { return t.Duration }
+
+func (t TurnInPlace) At(position geometry.Position, direction geometry.Direction, fraction float32) (geometry.Position, geometry.Direction) {
+	return position, direction.Add(geometry.Direction(t.Turn * fraction))
+}
+
+
+// Path is the motion of a single Dancer as a sequence of PathSteps.
+type Path struct {
+	Dancer dancer.Dancer
+	Start geometry.Position
+	StartDirection geometry.Direction
+	Steps []PathStep
+}
+
+// NewPath returns a Path, with no steps, which starts from the
+// Dancer's current Position and Direction.
+func NewPath(d dancer.Dancer) *Path {
+	return &Path{
+		Dancer: d,
+		Start: d.Position(),
+		StartDirection: d.Direction(),
+		Steps: []PathStep{},
+	}
+}
+
+// Straight adds a Straight step to the Path.  If beats is 0 the step
+// takes DefaultStepBeats.
+func (p *Path) Straight(to geometry.Position, facing geometry.Direction, beats float32) *Path {
+	return p.Add(Straight{ To: to, Facing: facing, Duration: defaultBeats(beats) })
+}
+
+// Arc adds an Arc step to the Path.  If beats is 0 the step takes
+// DefaultStepBeats.
+func (p *Path) Arc(pivot geometry.Position, turn float32, beats float32) *Path {
+	return p.Add(Arc{ Pivot: pivot, Turn: turn, Duration: defaultBeats(beats) })
+}
+
+// TurnInPlace adds a TurnInPlace step to the Path.  If beats is 0 the
+// step takes DefaultStepBeats.
+func (p *Path) TurnInPlace(turn float32, beats float32) *Path {
+	return p.Add(TurnInPlace{ Turn: turn, Duration: defaultBeats(beats) })
+}
+
+// Add adds a PathStep to the end of the Path.
+func (p *Path) Add(step PathStep) *Path {
+	p.Steps = append(p.Steps, step)
+	return p
+}
+
+func defaultBeats(beats float32) float32 {
+	if beats <= 0 {
+		return DefaultStepBeats
+	}
+	return beats
+}
+
+// Beats returns how long the whole Path takes.
+func (p *Path) Beats() float32 {
+	beats := float32(0)
+	for _, step := range p.Steps {
+		beats += step.Beats()
+	}
+	return beats
+}
+
+// At returns the Position and Direction of the Path's Dancer at the
+// specified number of beats from the start of the Path.
+func (p *Path) At(beat float32) (geometry.Position, geometry.Direction) {
+	position := p.Start
+	direction := p.StartDirection
+	for _, step := range p.Steps {
+		if beat < step.Beats() {
+			return step.At(position, direction, float32(math.Max(0, float64(beat / step.Beats()))))
+		}
+		position, direction = step.At(position, direction, 1)
+		beat -= step.Beats()
+	}
+	return position, direction
+}
+
+// End returns where the Path's Dancer ends up.
+func (p *Path) End() (geometry.Position, geometry.Direction) {
+	return p.At(p.Beats())
+}
+
+// Scale returns a copy of the Path that takes the specified number of
+// beats.  Each step keeps its share of the total.
+func (p *Path) Scale(beats float32) *Path {
+	scaled := &Path{
+		Dancer: p.Dancer,
+		Start: p.Start,
+		StartDirection: p.StartDirection,
+		Steps: []PathStep{},
+	}
+	total := p.Beats()
+	if total <= 0 {
+		return scaled
+	}
+	factor := beats / total
+	for _, step := range p.Steps {
+		switch s := step.(type) {
+		case Straight:
+			s.Duration *= factor
+			scaled.Add(s)
+		case Arc:
+			s.Duration *= factor
+			scaled.Add(s)
+		case TurnInPlace:
+			s.Duration *= factor
+			scaled.Add(s)
+		default:
+			scaled.Add(step)
+		}
+	}
+	return scaled
+}
+
+
+// RecordPaths adds DancerSnapshots to the Timeline showing the
+// Dancers moving along their Paths, starting at time start.  There
+// are ticksPerBeat snapshots per beat.  Snapshots are recorded for
+// the times after start up to and including when the longest Path
+// ends.  Dancers which have no Path, or whose Path has already ended,
+// are recorded where they are.  The last Time recorded is returned.
+func RecordPaths(tl Timeline, start Time, ticksPerBeat int, paths ...*Path) Time {
+	byDancer := map[dancer.Dancer]*Path{}
+	beats := float32(0)
+	for _, p := range paths {
+		byDancer[p.Dancer] = p
+		if b := p.Beats(); b > beats {
+			beats = b
+		}
+	}
+	ticks := int(math.Ceil(float64(beats * float32(ticksPerBeat))))
+	for tick := 1; tick <= ticks; tick++ {
+		beat := float32(tick) / float32(ticksPerBeat)
+		for _, d := range tl.Dancers() {
+			position, direction := d.Position(), d.Direction()
+			if p, ok := byDancer[d]; ok {
+				position, direction = p.At(beat)
+			}
+			tl.RecordSnapshot(d, start + Time(tick), position, direction)
+		}
+	}
+	return start + Time(ticks)
+}
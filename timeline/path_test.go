@@ -0,0 +1,55 @@
+package timeline
+
+import "testing"
+import "squaredance/dancer"
+import "squaredance/geometry"
+
+
+func TestPathAt(t *testing.T) {
+	d := dancer.MakeSomeDancers(1)[0]
+	d.Move(geometry.NewPositionDownLeft(0, 1), geometry.Direction0)
+	path := NewPath(d).
+		Arc(geometry.Origin, 0.25, 2).
+		Straight(geometry.NewPositionDownLeft(-2, 0), geometry.Direction1, 1).
+		TurnInPlace(-0.25, 1)
+	if want, got := float32(4), path.Beats(); got != want {
+		t.Errorf("Wrong number of beats: want %f, got %f", want, got)
+	}
+	check := func(beat float32, position geometry.Position, direction geometry.Direction) {
+		p, dir := path.At(beat)
+		if !p.Equal(position) || !dir.Equal(direction) {
+			t.Errorf("At beat %f: want %v %v, got %v %v",
+				beat, position, direction, p, dir)
+		}
+	}
+	check(0, geometry.NewPositionDownLeft(0, 1), geometry.Direction0)
+	// Half way around the Arc:
+	check(1, geometry.NewPosition(0.375, 1), geometry.Direction(0.125))
+	check(2, geometry.NewPositionDownLeft(-1, 0), geometry.Direction1)
+	check(2.5, geometry.NewPositionDownLeft(-1.5, 0), geometry.Direction1)
+	check(4, geometry.NewPositionDownLeft(-2, 0), geometry.Direction0)
+	// The Path holds still once it's done:
+	check(5, geometry.NewPositionDownLeft(-2, 0), geometry.Direction0)
+	if scaled := path.Scale(8); scaled.Beats() != 8 {
+		t.Errorf("Scale: want 8 beats, got %f", scaled.Beats())
+	}
+}
+
+func TestRecordPaths(t *testing.T) {
+	dancers := dancer.MakeSomeDancers(2)
+	dancers[0].Move(geometry.Origin, geometry.Direction0)
+	dancers[1].Move(geometry.NewPositionDownLeft(0, 1), geometry.Direction0)
+	tl := NewTimeline(dancers)
+	tl.MakeSnapshot(0)
+	path := NewPath(dancers[0]).Straight(geometry.NewPositionDownLeft(2, 0), geometry.Direction0, 2)
+	dancers[0].Move(path.End())
+	if want, got := Time(8), RecordPaths(tl, 0, 4, path); got != want {
+		t.Errorf("Wrong end time: want %d, got %d", want, got)
+	}
+	if want, got := geometry.NewPositionDownLeft(0.5, 0), tl.FindSnapshot(dancers[0], 2).Position(); !want.Equal(got) {
+		t.Errorf("Wrong position: want %v, got %v", want, got)
+	}
+	if want, got := dancers[1].Position(), tl.FindSnapshot(dancers[1], 8).Position(); !want.Equal(got) {
+		t.Errorf("Dancer without a path moved: want %v, got %v", want, got)
+	}
+}
@@ -34,6 +34,7 @@ type Timeline interface {
     	FindSnapshot(dancer.Dancer, Time) DancerSnapshot
 	FindSnapshots(dancer dancer.Dancer, start, end Time) []DancerSnapshot
 	MakeSnapshot(Time)
+	RecordSnapshot(dancer.Dancer, Time, geometry.Position, geometry.Direction)
 	// Bounds returns the most extreme coordinates of all dancers
 	// throughout the Timeline.
 	Bounds() (leftmost, rightmost geometry.Left, downmost, upmost geometry.Down)
@@ -66,14 +67,21 @@ func (tl *TimelineImpl) FindSnapshots(d dancer.Dancer, start, end Time) []Dancer
 // labeled with the specified Time.
 func (tl *TimelineImpl) MakeSnapshot(time Time) {
 	for _, d := range tl.Dancers() {
-		s := &DancerSnapshotImpl {
-			time: time,
-			dancer: d,
-			position: d.Position(),
-			direction: d.Direction(),
-		}
-		tl.snapshots = append(tl.snapshots, s)
+		tl.RecordSnapshot(d, time, d.Position(), d.Direction())
 	}
+}
+
+// RecordSnapshot records a DancerSnapshot for the Dancer at the
+// specified Time, Position and Direction, regardless of where the
+// Dancer currently is.
+func (tl *TimelineImpl) RecordSnapshot(d dancer.Dancer, time Time, position geometry.Position, direction geometry.Direction) {
+	s := &DancerSnapshotImpl {
+		time: time,
+		dancer: d,
+		position: position,
+		direction: direction,
+	}
+	tl.snapshots = append(tl.snapshots, s)
 	if time > tl.mostRecent {
 		tl.mostRecent = time
 	}
@@ -103,6 +111,9 @@ func ShowHistory(tl Timeline) {
 
 
 func (timeline *TimelineImpl) Bounds() (leftmost, rightmost geometry.Left, downmost, upmost geometry.Down) {
+	if len(timeline.snapshots) == 0 {
+		return 0, 0, 0, 0
+	}
 	p := timeline.snapshots[0].Position()
 	leftmost = p.Left
 	rightmost = p.Left
@@ -119,7 +130,7 @@ func (timeline *TimelineImpl) Bounds() (leftmost, rightmost geometry.Left, downm
 		if p.Down > downmost {
 			downmost = p.Down
 		}
-		if p.Down > upmost {
+		if p.Down < upmost {
 			upmost = p.Down
 		}
 		return true